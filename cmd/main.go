@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 
@@ -14,5 +16,20 @@ func main() {
 	exPath := filepath.Dir(ex)
 
 	godotenv.Load(fmt.Sprintf("%s/../.env", exPath))
-	server.InitializeEagleEye()
+
+	defaultMode := os.Getenv("EAGLEEYE_MODE")
+	if defaultMode == "" {
+		defaultMode = server.ModeStandalone
+	}
+
+	mode := flag.String("mode", defaultMode, "standalone, scheduler or worker")
+	flag.Parse()
+
+	switch *mode {
+	case server.ModeStandalone, server.ModeScheduler, server.ModeWorker:
+	default:
+		log.Fatalf("[!] Unknown mode %q", *mode)
+	}
+
+	server.InitializeEagleEye(*mode)
 }
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// SubdomainCreated carries the ids of newly inserted, in scope subdomains.
+	SubdomainCreated = "subdomain-created"
+	// DnsActivated carries the ids of subdomains that started resolving.
+	DnsActivated = "dns-activated"
+	// HttpServiceCreated carries the ids of http services that started
+	// answering.
+	HttpServiceCreated = "http-service-created"
+)
+
+type Event struct {
+	Kind string
+	IDs  []primitive.ObjectID
+}
+
+type Handler func(Event)
+
+type subscription struct {
+	handler Handler
+}
+
+// Bus lets the stages of the pipeline react to assets as soon as another stage
+// finds them, instead of waiting for their next scheduled run. Handlers run on
+// their own goroutine, so a slow subscriber never holds back the publisher.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]*subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[string][]*subscription{}}
+}
+
+// Subscribe calls handler for every event of kind, until the returned
+// function is called.
+func (b *Bus) Subscribe(kind string, handler Handler) func() {
+	sub := &subscription{handler: handler}
+
+	b.mu.Lock()
+	b.subs[kind] = append(b.subs[kind], sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		subs := b.subs[kind]
+		for i := range subs {
+			if subs[i] == sub {
+				b.subs[kind] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (b *Bus) Publish(event Event) {
+	if len(event.IDs) == 0 {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs[event.Kind] {
+		go sub.handler(event)
+	}
+}
+
+// Debounce batches the events it's handed and passes them to flush once wait
+// went by since the first one, so a burst of findings ends up in one run.
+func Debounce(wait time.Duration, flush func([]Event)) Handler {
+	var mu sync.Mutex
+	var batch []Event
+
+	return func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		batch = append(batch, event)
+		if len(batch) != 1 {
+			return
+		}
+
+		time.AfterFunc(wait, func() {
+			mu.Lock()
+			events := batch
+			batch = nil
+			mu.Unlock()
+
+			flush(events)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collection = "target-versions"
+
+// Record stores the current state of target as a new version, unless nothing
+// changed since the latest one. It returns the version that was stored, or
+// nil if none was needed.
+func Record(ctx context.Context, db *mongo.Database, target *m.Target, origin string) (*m.TargetVersion, error) {
+	// Two writers racing for the same version number only happens when edits
+	// overlap, one retry with the fresh latest version is enough.
+	for attempt := 0; attempt < 2; attempt++ {
+		latest, err := Latest(ctx, db, target)
+		if err != nil {
+			return nil, err
+		}
+
+		version := m.NewTargetVersion(latest, target, origin)
+		if latest != nil && version.Diff.IsEmpty() {
+			return nil, nil
+		}
+
+		_, err = db.Collection(collection).InsertOne(ctx, version)
+		if err == nil {
+			return version, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("[!] Error while saving version of %s: %w", target.Name, err)
+		}
+	}
+
+	return nil, fmt.Errorf("[!] Error while saving version of %s: concurrent edits", target.Name)
+}
+
+// Seed stores the current state of target as its first version if it has
+// none. Targets created before the history existed get one this way before
+// they're first changed, so their history starts with what they were.
+func Seed(ctx context.Context, db *mongo.Database, target *m.Target) error {
+	latest, err := Latest(ctx, db, target)
+	if err != nil || latest != nil {
+		return err
+	}
+
+	_, err = Record(ctx, db, target, "seed")
+	return err
+}
+
+// Latest returns the most recent version of target, or nil if it has none.
+func Latest(ctx context.Context, db *mongo.Database, target *m.Target) (*m.TargetVersion, error) {
+	opts := options.FindOne().SetSort(bson.M{"version": -1})
+
+	var latest m.TargetVersion
+	err := db.Collection(collection).FindOne(ctx, bson.M{"target": target.ID}, opts).Decode(&latest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching latest version of %s: %w", target.Name, err)
+	}
+
+	return &latest, nil
+}
+
+// List returns every version of target, oldest first.
+func List(ctx context.Context, db *mongo.Database, target *m.Target) ([]m.TargetVersion, error) {
+	opts := options.Find().SetSort(bson.M{"version": 1})
+
+	cursor, err := db.Collection(collection).Find(ctx, bson.M{"target": target.ID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching versions of %s: %w", target.Name, err)
+	}
+
+	versions := []m.TargetVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching versions of %s: %w", target.Name, err)
+	}
+
+	return versions, nil
+}
+
+// Get returns a single version of target.
+func Get(ctx context.Context, db *mongo.Database, target *m.Target, version int) (*m.TargetVersion, error) {
+	var v m.TargetVersion
+
+	err := db.Collection(collection).FindOne(ctx, bson.M{"target": target.ID, "version": version}).Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Delete drops every version of target, it returns how many there were.
+func Delete(ctx context.Context, db *mongo.Database, target *m.Target) (int64, error) {
+	rs, err := db.Collection(collection).DeleteMany(ctx, bson.M{"target": target.ID})
+	if err != nil {
+		return 0, fmt.Errorf("[!] Error while deleting versions of %s: %w", target.Name, err)
+	}
+
+	return rs.DeletedCount, nil
+}
+
+// CreateIndexes makes sure a target can't end up with two equal version numbers.
+func CreateIndexes(ctx context.Context, db *mongo.Database) error {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "target", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := db.Collection(collection).Indexes().CreateOne(ctx, index)
+	return err
+}
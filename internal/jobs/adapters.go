@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ArCaneSec/eagleeye/internal/tools"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// toolConfigs loads how the tools run through adapters are configured, a
+// tool missing from it runs with its defaults.
+func (d *Dependencies) toolConfigs(ctx context.Context) (map[string]m.ToolConfig, error) {
+	var config struct {
+		Tools map[string]m.ToolConfig `bson:"tools"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "tools": 1})
+	err := d.db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("[!] Error while fetching tools config from db: %w", err)
+	}
+
+	return config.Tools, nil
+}
+
+// loadAdapter builds the adapter of the tool called name, running its binary
+// in pg like any other command of the task. The version of the binary is
+// logged the first time it's loaded, which also tells early about a missing
+// one.
+func loadAdapter[T tools.Tool](ctx context.Context, d *Dependencies, pg *procGroup, name string) (T, error) {
+	var adapter T
+
+	configs, err := d.toolConfigs(ctx)
+	if err != nil {
+		return adapter, err
+	}
+
+	run := func(ctx context.Context, command string, args ...string) (string, error) {
+		return d.execute(ctx, pg, command, args...)
+	}
+
+	tool, err := tools.New(name, configs[name], run)
+	if err != nil {
+		return adapter, err
+	}
+
+	adapter, ok := tool.(T)
+	if !ok {
+		return adapter, fmt.Errorf("[!] %s can't be used by this task", name)
+	}
+
+	d.logVersion(ctx, tool, configs[name].Path)
+	return adapter, nil
+}
+
+// logVersion logs the version of tool once per process and binary. It's
+// asked again on the next load if it couldn't be detected.
+func (d *Dependencies) logVersion(ctx context.Context, tool tools.Tool, path string) {
+	key := tool.Name() + "@" + path
+	if _, seen := d.versions.LoadOrStore(key, struct{}{}); seen {
+		return
+	}
+
+	version, err := tool.Version(ctx)
+	if err != nil {
+		d.versions.Delete(key)
+		log.Println(err)
+		return
+	}
+
+	log.Printf("[*] Running %s (%s).\n", tool.Name(), version)
+}
+
+// ToolVersion asks the binary of the tool called name for its version,
+// through the same limits as the commands of tasks.
+func (s *Scheduler) ToolVersion(ctx context.Context, name string, config m.ToolConfig) (string, error) {
+	var pg procGroup
+	run := func(ctx context.Context, command string, args ...string) (string, error) {
+		return s.deps.execute(ctx, &pg, command, args...)
+	}
+
+	tool, err := tools.New(name, config, run)
+	if err != nil {
+		return "", err
+	}
+
+	return tool.Version(ctx)
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type versionedTool struct {
+	asked atomic.Int32
+	err   error
+}
+
+func (v *versionedTool) Name() string {
+	return "subfinder"
+}
+
+func (v *versionedTool) Version(ctx context.Context) (string, error) {
+	v.asked.Add(1)
+	return "v1.0.0", v.err
+}
+
+func TestLogVersionOnce(t *testing.T) {
+	var d Dependencies
+	tool := &versionedTool{}
+
+	// Like a task starting the tool for many domains at once.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.logVersion(context.Background(), tool, "")
+		}()
+	}
+	wg.Wait()
+
+	if n := tool.asked.Load(); n != 1 {
+		t.Errorf("version asked %d times, want once", n)
+	}
+
+	// Another binary of the same tool is asked on its own.
+	d.logVersion(context.Background(), tool, "/opt/subfinder")
+	if n := tool.asked.Load(); n != 2 {
+		t.Errorf("version asked %d times after a new path, want 2", n)
+	}
+
+	// A failed detection is tried again on the next load.
+	failing := &versionedTool{err: errors.New("not found")}
+	d.logVersion(context.Background(), failing, "/missing")
+	d.logVersion(context.Background(), failing, "/missing")
+	if n := failing.asked.Load(); n != 2 {
+		t.Errorf("failed version asked %d times, want 2", n)
+	}
+}
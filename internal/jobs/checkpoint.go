@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// checkpoint is the progress of a step through its units of work, e.g. the
+// root domains of every target. It outlives runs that time out or get
+// killed, so the next run picks up where they stopped, and is dropped once a
+// run got through every unit.
+type checkpoint struct {
+	Job     primitive.ObjectID `bson:"job"`
+	Step    string             `bson:"step"`
+	Done    []string           `bson:"done"`
+	Started time.Time          `bson:"started"`
+	Updated time.Time          `bson:"updated"`
+
+	deps *Dependencies
+	mu   sync.Mutex
+	done map[string]bool
+}
+
+// loadCheckpoint returns the checkpoint of the running step, nil if the run
+// doesn't cover everything (e.g. it was asked for a single target, or only
+// for the targets that came due) and so can't tell how far a full one got.
+func (d *Dependencies) loadCheckpoint(ctx context.Context) (*checkpoint, error) {
+	params := paramsFrom(ctx)
+	if params.job.IsZero() || params.Target != "" || params.due != nil {
+		return nil, nil
+	}
+
+	cp := &checkpoint{Job: params.job, Step: params.step}
+	filter := bson.M{"job": cp.Job, "step": cp.Step}
+
+	err := d.db.Collection("checkpoints").FindOne(ctx, filter).Decode(cp)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("[!] Error while loading checkpoint of %s: %w", cp.Step, err)
+	}
+
+	cp.deps = d
+	cp.done = make(map[string]bool, len(cp.Done))
+	for _, key := range cp.Done {
+		cp.done[key] = true
+	}
+
+	if len(cp.Done) != 0 {
+		log.Printf("[~] Resuming %s, %d units are already done.\n", cp.Step, len(cp.Done))
+	}
+
+	return cp, nil
+}
+
+func (c *checkpoint) isDone(key string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.done[key]
+}
+
+// markDone records a unit as done, so a later run doesn't go through it again.
+// Failed units are left out for the next run to retry.
+func (c *checkpoint) markDone(ctx context.Context, key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.done[key] = true
+	c.mu.Unlock()
+
+	now := time.Now()
+	update := bson.M{
+		"$addToSet":    bson.M{"done": key},
+		"$set":         bson.M{"updated": now},
+		"$setOnInsert": bson.M{"started": now},
+	}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := c.deps.db.Collection("checkpoints").UpdateOne(ctx, bson.M{"job": c.Job, "step": c.Step}, update, opts)
+	if err != nil {
+		log.Printf("[!] Error while saving checkpoint of %s: %v\n", c.Step, err)
+	}
+}
+
+// clear drops the checkpoint once every unit is done.
+func (c *checkpoint) clear(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	if _, err := c.deps.db.Collection("checkpoints").DeleteOne(ctx, bson.M{"job": c.Job, "step": c.Step}); err != nil {
+		log.Printf("[!] Error while clearing checkpoint of %s: %v\n", c.Step, err)
+	}
+}
+
+// ForgetTarget drops the units of a deleted target from every checkpoint, it
+// returns how many checkpoints had some. Units are keyed by target id, see
+// enumUnit.key.
+func ForgetTarget(ctx context.Context, db *mongo.Database, target primitive.ObjectID) (int64, error) {
+	pattern := primitive.Regex{Pattern: "^" + target.Hex() + "/"}
+	update := bson.M{"$pull": bson.M{"done": pattern}}
+
+	rs, err := db.Collection("checkpoints").UpdateMany(ctx, bson.M{"done": pattern}, update)
+	if err != nil {
+		return 0, fmt.Errorf("[!] Error while dropping checkpoints of %s: %w", target.Hex(), err)
+	}
+
+	return rs.ModifiedCount, nil
+}
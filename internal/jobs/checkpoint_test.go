@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLoadCheckpointPartialRuns(t *testing.T) {
+	job := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		params RunParams
+	}{
+		{name: "outside of a job", params: RunParams{}},
+		{name: "single target", params: RunParams{Target: "example", job: job, step: "enum"}},
+		{name: "due targets", params: RunParams{due: []primitive.ObjectID{primitive.NewObjectID()}, job: job, step: "enum"}},
+	}
+
+	// Without a db, reaching for the checkpoint would panic.
+	var d Dependencies
+	for _, test := range tests {
+		cp, err := d.loadCheckpoint(withParams(context.Background(), test.params))
+		if cp != nil || err != nil {
+			t.Errorf("%s: loadCheckpoint = %v, %v, want no checkpoint", test.name, cp, err)
+		}
+	}
+}
+
+func TestForgetTarget(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("forget", func(mt *mtest.T) {
+		target := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}))
+
+		n, err := ForgetTarget(context.Background(), mt.DB, target)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if n != 2 {
+			mt.Errorf("ForgetTarget = %d, want 2", n)
+		}
+
+		event := mt.GetStartedEvent()
+		if event == nil || event.CommandName != "update" {
+			mt.Fatal("no update was sent")
+		}
+		update := event.Command.Lookup("updates").Array().Index(0).Value().Document()
+		pattern, options := update.Lookup("u", "$pull", "done").Regex()
+		re := regexp.MustCompile(pattern)
+		if options != "" || !update.Lookup("multi").Boolean() {
+			mt.Fatalf("update %s doesn't pull from every checkpoint", update)
+		}
+
+		unit := enumUnit{target: &m.Target{ID: target}, domain: "example.com"}
+		other := enumUnit{target: &m.Target{ID: primitive.NewObjectID()}, domain: "example.com"}
+		if !re.MatchString(unit.key()) || re.MatchString(other.key()) {
+			mt.Errorf("pattern %q doesn't pick the units of the target alone", pattern)
+		}
+	})
+}
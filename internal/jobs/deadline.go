@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// maxGrace caps the time a step keeps after its tools are stopped to store
+// what they found.
+const maxGrace = 5 * time.Minute
+
+// errSoftDeadline marks tools stopped at their step's soft deadline, whatever
+// they printed until then is still worth storing.
+var errSoftDeadline = errors.New("soft deadline reached")
+
+type softDeadlineKey struct{}
+
+// withSoftDeadline sets the soft deadline of a step a tenth of its time, up
+// to maxGrace, before its hard one. Tools are stopped and no new work is
+// started past it, the rest is left to store partial results.
+func withSoftDeadline(ctx context.Context) context.Context {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+
+	grace := min(time.Until(deadline)/10, maxGrace)
+	return context.WithValue(ctx, softDeadlineKey{}, deadline.Add(-grace))
+}
+
+// softDeadline returns the soft deadline of the running step, if it has one.
+func softDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(softDeadlineKey{}).(time.Time)
+	return deadline, ok
+}
+
+// workContext returns a context done at the soft deadline of ctx, for tools
+// and for deciding whether to start more work.
+func workContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := softDeadline(ctx); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,296 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+	"github.com/ArCaneSec/eagleeye/pkg/platforms"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type taskBuilder func(d *Dependencies, def m.TaskDefinition, selector m.LabelSelector) Task
+
+var taskBuilders = map[string]taskBuilder{
+	"subdomain-enumeration": func(d *Dependencies, def m.TaskDefinition, _ m.LabelSelector) Task {
+		return &SubdomainEnumeration{
+			Dependencies: d,
+			scriptPath:   def.ScriptPath,
+			retry:        newRetryPolicy(def.Retry),
+			workers:      max(def.Concurrency, 1),
+			toolNames:    def.Tools,
+		}
+	},
+	"dns-resolve": func(d *Dependencies, def m.TaskDefinition, _ m.LabelSelector) Task {
+		return &DnsResolve{Dependencies: d, scriptPath: def.ScriptPath, tool: singleTool(def)}
+	},
+	"dns-resolve-all": func(d *Dependencies, def m.TaskDefinition, selector m.LabelSelector) Task {
+		return &DnsResolveAll{
+			DnsResolve: &DnsResolve{Dependencies: d, scriptPath: def.ScriptPath, tool: singleTool(def)},
+			selector:   selector,
+		}
+	},
+	"http-discovery": func(d *Dependencies, def m.TaskDefinition, _ m.LabelSelector) Task {
+		return &HttpDiscovery{Dependencies: d, scriptPath: def.ScriptPath, tool: singleTool(def)}
+	},
+	"http-discovery-all": func(d *Dependencies, def m.TaskDefinition, selector m.LabelSelector) Task {
+		return &HttpDiscoveryAll{
+			HttpDiscovery: &HttpDiscovery{Dependencies: d, scriptPath: def.ScriptPath, tool: singleTool(def)},
+			selector:      selector,
+		}
+	},
+	"update-nuclei": func(d *Dependencies, def m.TaskDefinition, _ m.LabelSelector) Task {
+		return &UpdateNuclei{Dependencies: d, scriptPath: def.ScriptPath}
+	},
+	"run-new-templates": func(d *Dependencies, def m.TaskDefinition, selector m.LabelSelector) Task {
+		return &RunNewTemplates{Dependencies: d, scriptPath: def.ScriptPath, tool: singleTool(def), selector: selector}
+	},
+	"scope-sync": func(d *Dependencies, def m.TaskDefinition, _ m.LabelSelector) Task {
+		return &ScopeSync{Dependencies: d, client: platforms.NewClient(platforms.EndpointsFromEnv())}
+	},
+}
+
+// singleTool returns the tool of the tasks taking a single one, if it's set.
+func singleTool(def m.TaskDefinition) string {
+	if len(def.Tools) == 0 {
+		return ""
+	}
+
+	return def.Tools[0]
+}
+
+func buildTask(d *Dependencies, def m.TaskDefinition) (Task, error) {
+	builder, ok := taskBuilders[def.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown task type %q", def.Type)
+	}
+
+	selector, err := m.ParseLabelSelector(def.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return builder(d, def, selector), nil
+}
+
+func buildJob(d *Dependencies, def m.JobDefinition) (*job, error) {
+	if errs := def.Validate(); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid job definition %s: %v", def.Name, errs)
+	}
+
+	graph := def.Graph()
+	budgets := def.StepBudgets()
+	steps := make([]step, 0, len(graph))
+	for _, stepDef := range graph {
+		if stepDef.Retry == nil {
+			stepDef.Retry = def.DefaultRetry
+		}
+
+		task, err := buildTask(d, stepDef.TaskDefinition)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step{
+			def:    stepDef,
+			task:   task,
+			retry:  newRetryPolicy(stepDef.Retry),
+			budget: budgets[stepDef.Name],
+		})
+	}
+
+	return &job{
+		def:      def,
+		deps:     d,
+		duration: def.IntervalDuration(),
+		steps:    steps,
+		state:    newRunState(),
+	}, nil
+}
+
+// loadDefinitions returns the stored job definitions, seeding the jobs
+// collection with the default ones on the first start.
+func loadDefinitions(db *mongo.Database) ([]m.JobDefinition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("jobs")
+
+	if err := seedDefinitions(ctx, db); err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []m.JobDefinition
+	if err := cursor.All(ctx, &defs); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+// seedDefinitions adds the default jobs missing from the jobs collection by
+// name, so defaults shipped after a database was first seeded show up as
+// well. Every default is seeded once, the names are recorded under
+// seededJobs in the config collection and a default deleted afterwards stays
+// deleted.
+func seedDefinitions(ctx context.Context, db *mongo.Database) error {
+	var config struct {
+		SeededJobs []string `bson:"seededJobs"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "seededJobs": 1})
+	err := db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("[!] Error while fetching seeded jobs: %w", err)
+	}
+
+	var seeded []string
+	for _, def := range defaultJobs() {
+		if slices.Contains(config.SeededJobs, def.Name) {
+			continue
+		}
+
+		filter := bson.M{"name": def.Name}
+		update := bson.M{"$setOnInsert": def}
+		rs, err := db.Collection("jobs").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("[!] Error while seeding the default job %s: %w", def.Name, err)
+		}
+
+		if rs.UpsertedCount != 0 {
+			log.Printf("[+] Seeded the default job %s.\n", def.Name)
+		}
+		seeded = append(seeded, def.Name)
+	}
+
+	if len(seeded) == 0 {
+		return nil
+	}
+
+	update := bson.M{"$addToSet": bson.M{"seededJobs": bson.M{"$each": seeded}}}
+	if _, err := db.Collection("config").UpdateOne(ctx, bson.M{}, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("[!] Error while recording seeded jobs: %w", err)
+	}
+
+	return nil
+}
+
+// scriptsDir is where the scripts of the default jobs are looked for,
+// EAGLEEYE_SCRIPTS or the scripts directory next to the executable's one,
+// like .env is.
+func scriptsDir() string {
+	if dir := os.Getenv("EAGLEEYE_SCRIPTS"); dir != "" {
+		return dir
+	}
+
+	ex, err := os.Executable()
+	if err != nil {
+		return "scripts"
+	}
+	return filepath.Join(filepath.Dir(ex), "..", "scripts")
+}
+
+// defaultJobs are the jobs EagleEye used to ship hardcoded, only enumeration
+// and scope sync are active out of the box. There are no scripts for dns
+// resolution and http discovery, they run dnsx and httpx through adapters.
+func defaultJobs() []m.JobDefinition {
+	dir := scriptsDir()
+	script := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	return []m.JobDefinition{
+		{
+			Name: "subdomain-enumeration",
+			TaskDefinition: m.TaskDefinition{
+				Type:       "subdomain-enumeration",
+				ScriptPath: script("enumerate.sh"),
+			},
+			Interval: "48h",
+			Timeout:  "2h",
+			Active:   true,
+			// Enumeration gets what's left of the timeout, 1h30m.
+			SubTasks: []m.TaskDefinition{
+				{Type: "dns-resolve", Tools: []string{"dnsx"}, Timeout: "15m"},
+				{Type: "http-discovery", Tools: []string{"httpx"}, Timeout: "15m"},
+			},
+		},
+		{
+			Name: "dns-resolve-all",
+			TaskDefinition: m.TaskDefinition{
+				Type:  "dns-resolve-all",
+				Tools: []string{"dnsx"},
+			},
+			Interval: "48h",
+			Timeout:  "2h",
+		},
+		{
+			Name: "http-discovery-all",
+			TaskDefinition: m.TaskDefinition{
+				Type:  "http-discovery-all",
+				Tools: []string{"httpx"},
+			},
+			Interval: "48h",
+			Timeout:  "2h",
+		},
+		{
+			Name: "dns-resolve-new",
+			TaskDefinition: m.TaskDefinition{
+				Type:  "dns-resolve",
+				Tools: []string{"dnsx"},
+			},
+			Timeout: "30m",
+			On:      []string{"subdomain-created"},
+		},
+		{
+			Name: "http-discovery-new",
+			TaskDefinition: m.TaskDefinition{
+				Type:  "http-discovery",
+				Tools: []string{"httpx"},
+			},
+			Timeout: "30m",
+			On:      []string{"dns-activated"},
+		},
+		{
+			Name: "update-nuclei",
+			TaskDefinition: m.TaskDefinition{
+				Type:       "update-nuclei",
+				ScriptPath: script("update-nuclei.sh"),
+			},
+			Interval: "24h",
+			Timeout:  "2h",
+			SubTasks: []m.TaskDefinition{
+				{Type: "run-new-templates", ScriptPath: script("nuclei-new-templates.sh"), Timeout: "1h30m"},
+			},
+		},
+		{
+			Name: "run-new-templates",
+			TaskDefinition: m.TaskDefinition{
+				Type:       "run-new-templates",
+				ScriptPath: script("nuclei-new-templates.sh"),
+			},
+			Interval: "24h",
+			Timeout:  "2h",
+		},
+		{
+			Name:           "scope-sync",
+			TaskDefinition: m.TaskDefinition{Type: "scope-sync"},
+			Interval:       "12h",
+			Timeout:        "30m",
+			Active:         true,
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDefaultJobs(t *testing.T) {
+	t.Setenv("EAGLEEYE_SCRIPTS", "../../scripts")
+
+	for _, def := range defaultJobs() {
+		if errs := def.Validate(); len(errs) != 0 {
+			t.Errorf("%s: Validate() = %v", def.Name, errs)
+		}
+
+		for _, step := range def.Graph() {
+			switch {
+			case step.ScriptPath != "":
+				if _, err := os.Stat(step.ScriptPath); err != nil {
+					t.Errorf("%s: step %s runs a script that isn't shipped: %v", def.Name, step.Name, err)
+				}
+			case len(step.Tools) == 0 && step.Type != "scope-sync":
+				t.Errorf("%s: step %s has neither a script nor a tool", def.Name, step.Name)
+			}
+		}
+	}
+}
@@ -2,50 +2,86 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/ArCaneSec/eagleeye/internal/events"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (d *DnsResolve) Start(ctx context.Context, isSubTask bool) {
-	startRegularTask(ctx, d, d.Dependencies.wg, isSubTask)
+func (d *DnsResolve) Start(ctx context.Context) (RunStats, error) {
+	return startRegularTask(ctx, d, d.Dependencies.wg)
 }
 
-func (d *DnsResolveAll) Start(ctx context.Context, isSubTask bool) {
-	startRegularTask(ctx, d, d.Dependencies.wg, isSubTask)
+func (d *DnsResolveAll) Start(ctx context.Context) (RunStats, error) {
+	ctx, due, err := d.narrowToDue(ctx, m.ScheduleResolution)
+	if err != nil || !due {
+		return RunStats{}, err
+	}
+
+	stats, err := startRegularTask(ctx, d, d.Dependencies.wg)
+	if err == nil {
+		d.markHandled(ctx, m.ScheduleResolution, d.selector)
+	}
+	return stats, err
 }
 
-func (d *DnsResolve) fetchAssets(ctx context.Context) error {
-	cursor, _ := d.db.Collection("subdomains").Find(
-		ctx,
-		bson.D{{"dns", nil}})
+func (d *DnsResolve) fetchAssets(ctx context.Context) (int, error) {
+	filter, err := subdomainsFilter(ctx, d.db, m.LabelSelector{}, bson.M{"dns": nil})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, _ := d.db.Collection("subdomains").Find(ctx, filter)
 
 	if err := cursor.All(ctx, &d.subdomains); err != nil {
-		return fmt.Errorf("[!] Error while fetching new subs: %w", err)
+		return 0, fmt.Errorf("[!] Error while fetching new subs: %w", err)
 	}
 
-	return nil
+	err = d.excludeOutOfScope(ctx)
+	return len(d.subdomains), err
 }
 
-func (d *DnsResolveAll) fetchAssets(ctx context.Context) error {
-	cursor, _ := d.db.Collection("subdomains").Find(ctx, bson.D{{}})
+func (d *DnsResolveAll) fetchAssets(ctx context.Context) (int, error) {
+	filter, err := subdomainsFilter(ctx, d.db, d.selector, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, _ := d.db.Collection("subdomains").Find(ctx, filter)
 	if err := cursor.All(ctx, &d.subdomains); err != nil {
-		return fmt.Errorf("[!] Error while fetching targets: %w", err)
+		return 0, fmt.Errorf("[!] Error while fetching targets: %w", err)
 	}
 
-	return nil
+	err = d.excludeOutOfScope(ctx)
+	return len(d.subdomains), err
+}
+
+func (d *DnsResolve) excludeOutOfScope(ctx context.Context) error {
+	guard, err := newScopeGuard(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	d.subdomains, err = guard.filterSubdomains(ctx, d.subdomains)
+	return err
 }
 
 func (d *DnsResolve) runCommand(ctx context.Context) (string, error) {
+	if d.tool != "" {
+		return d.runResolver(ctx)
+	}
+
 	tempFile, subsMap, err := tempFileNSubsMap(d.subdomains)
 
 	if err != nil {
@@ -56,7 +92,11 @@ func (d *DnsResolve) runCommand(ctx context.Context) (string, error) {
 
 	defer os.Remove(tempFile)
 
-	op, err := execute(ctx, &d.pgid, d.scriptPath, tempFile)
+	op, err := d.execute(ctx, &d.procGroup, d.scriptPath, tempFile)
+	d.partial = errors.Is(err, errSoftDeadline)
+	if d.partial {
+		return op, err
+	}
 	if err != nil {
 		return "", fmt.Errorf("[!] Error while resolving all subdomains: %w, %s", err, op)
 	}
@@ -64,6 +104,38 @@ func (d *DnsResolve) runCommand(ctx context.Context) (string, error) {
 	return op, nil
 }
 
+// runResolver is runCommand for tasks running their resolver through its
+// adapter.
+func (d *DnsResolve) runResolver(ctx context.Context) (string, error) {
+	resolver, err := loadAdapter[tools.Resolver](ctx, d.Dependencies, &d.procGroup, d.tool)
+	if err != nil {
+		return "", err
+	}
+
+	d.subsMap = make(map[string]*m.Subdomain, len(d.subdomains))
+	names := make([]string, 0, len(d.subdomains))
+	for i := range d.subdomains {
+		d.subsMap[d.subdomains[i].Subdomain] = &d.subdomains[i]
+		names = append(names, d.subdomains[i].Subdomain)
+	}
+
+	resolved, err := resolver.Resolve(ctx, names)
+	d.partial = errors.Is(err, errSoftDeadline)
+	if err != nil && !d.partial {
+		return "", fmt.Errorf("[!] Error while resolving all subdomains: %w", err)
+	}
+
+	// Only what was asked for, insertDB looks every line up.
+	known := make([]string, 0, len(resolved))
+	for _, name := range resolved {
+		if _, ok := d.subsMap[name]; ok {
+			known = append(known, name)
+		}
+	}
+
+	return strings.Join(known, "\n"), err
+}
+
 func (d *DnsResolve) checkResults(result string) ([]string, error) {
 	resolvedSubs := strings.Split(strings.TrimSpace(result), "\n")
 	if len(resolvedSubs) == 1 && resolvedSubs[0] == "" {
@@ -74,11 +146,12 @@ func (d *DnsResolve) checkResults(result string) ([]string, error) {
 	return resolvedSubs, nil
 }
 
-func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
+func (d *DnsResolve) insertDB(ctx context.Context, subs []string) (int, error) {
 	now := time.Now()
 	updates := make([]mongo.WriteModel, 0, len(d.subdomains))
 	https := make([]interface{}, 0, len(subs)*2)
 	newResolvedSubs := make([]string, 0, len(subs))
+	newResolvedIDs := make([]primitive.ObjectID, 0, len(subs))
 
 	for _, resolvedSub := range subs {
 		subObj := d.subsMap[resolvedSub]
@@ -90,9 +163,11 @@ func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
 					SetFilter(bson.M{"_id": subObj.ID}).
 					SetUpdate(bson.D{{"$set", bson.D{{"dns", &m.Dns{IsActive: true, Created: now, Updated: now}}}}}))
 			newResolvedSubs = append(newResolvedSubs, resolvedSub)
+			newResolvedIDs = append(newResolvedIDs, subObj.ID)
 		} else {
 			if !subObj.Dns.IsActive {
 				newResolvedSubs = append(newResolvedSubs, resolvedSub)
+				newResolvedIDs = append(newResolvedIDs, subObj.ID)
 			}
 			updates = append(
 				updates,
@@ -104,6 +179,12 @@ func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
 		delete(d.subsMap, resolvedSub)
 	}
 
+	// Subdomains missing from a run stopped at its soft deadline may just not
+	// have been tried, they keep their records.
+	if d.partial {
+		clear(d.subsMap)
+	}
+
 	for _, notResolvedSub := range d.subsMap {
 		if notResolvedSub.Dns == nil {
 			updates = append(
@@ -122,7 +203,7 @@ func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
 	// ses.StartTransaction()
 	_, err := d.db.Collection("subdomains").BulkWrite(ctx, updates)
 	if err != nil {
-		return fmt.Errorf(
+		return 0, fmt.Errorf(
 			"[!] Error updating all subdomains with new resolved subs: %w", err,
 		)
 	}
@@ -132,7 +213,7 @@ func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
 
 	_, err = d.db.Collection("http-services").InsertMany(ctx, https, httpOpts)
 	if err != nil && !mongo.IsDuplicateKeyError(err) {
-		return fmt.Errorf(
+		return 0, fmt.Errorf(
 			"[!] Error while creating empty http objects for resolved subs: %w", err,
 		)
 	}
@@ -141,14 +222,7 @@ func (d *DnsResolve) insertDB(ctx context.Context, subs []string) error {
 	if len(newResolvedSubs) != 0 {
 		log.Printf("[+] Found %d new dns records.\n", len(newResolvedSubs))
 		d.notify.NewDnsNotif(newResolvedSubs)
+		d.publish(ctx, events.Event{Kind: events.DnsActivated, IDs: newResolvedIDs})
 	}
-	return nil
-}
-
-func (d *DnsResolve) ErrNotif(err error) {
-	d.notify.ErrNotif(err)
-}
-
-func (d *DnsResolve) Kill() {
-	syscall.Kill(-d.pgid, syscall.SIGKILL)
+	return len(newResolvedSubs), nil
 }
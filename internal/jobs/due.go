@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastRunFields are the target fields recording when each kind of schedule
+// last went through the target.
+var lastRunFields = map[string]string{
+	m.ScheduleEnumeration: "lastEnumerated",
+	m.ScheduleResolution:  "lastResolved",
+	m.ScheduleScanning:    "lastScanned",
+}
+
+// scheduleKinds are the schedules the tasks honouring one go by.
+var scheduleKinds = map[string]string{
+	"subdomain-enumeration": m.ScheduleEnumeration,
+	"dns-resolve-all":       m.ScheduleResolution,
+	"run-new-templates":     m.ScheduleScanning,
+}
+
+// dueTick is how often jobs are checked for targets that came due between
+// their runs.
+const dueTick = 10 * time.Minute
+
+// scheduleClasses loads the schedules of bounty and non bounty targets, nil
+// if none were configured.
+func (d *Dependencies) scheduleClasses(ctx context.Context) (*m.ScheduleClasses, error) {
+	var config struct {
+		Schedules *m.ScheduleClasses `bson:"schedules"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "schedules": 1})
+	err := d.db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("[!] Error while fetching schedules from db: %w", err)
+	}
+
+	return config.Schedules, nil
+}
+
+// narrowToDue narrows a run covering every target down to the ones due for
+// kind, it returns false if none of them is. A run asked for a target only
+// waits for its scan window, and runs on the assets of events aren't narrowed
+// down at all. Targets left out by their scan window are recorded in the
+// returned context's params.
+func (d *Dependencies) narrowToDue(ctx context.Context, kind string) (context.Context, bool, error) {
+	params := paramsFrom(ctx)
+	if len(params.Subdomains) != 0 || len(params.Services) != 0 {
+		return ctx, true, nil
+	}
+
+	classes, targets, err := d.scheduledTargets(ctx, kind, bson.M{})
+	if err != nil {
+		return ctx, false, err
+	}
+
+	now := time.Now()
+	due := make([]primitive.ObjectID, 0, len(targets))
+	for i := range targets {
+		target := &targets[i]
+
+		if params.Target == "" && !classes.IsDue(target, kind, now) {
+			continue
+		}
+
+		// Runs on the due tick are only for targets with an interval, the
+		// rest go with the job's own schedule.
+		if params.trigger == m.TriggerDue && classes.Interval(target, kind) == 0 {
+			continue
+		}
+
+		// Only scanning is active enough for scan windows to matter.
+		if ok, reason := target.ScanWindow.Open(now); kind == m.ScheduleScanning && !ok {
+			log.Printf("[~] Postponing %s of %s, %s.\n", kind, target.Name, reason)
+			params.postponed = append(params.postponed, target.ID)
+			continue
+		}
+
+		due = append(due, target.ID)
+	}
+
+	switch {
+	case len(due) == 0:
+		log.Printf("[~] No target is due for %s.\n", kind)
+		return withParams(ctx, params), false, nil
+	case len(due) == len(targets):
+		return ctx, true, nil
+	}
+
+	log.Printf("[~] %d of %d targets are due for %s.\n", len(due), len(targets), kind)
+	params.due = due
+	return withParams(ctx, params), true, nil
+}
+
+// scheduledTargets returns the schedule classes and the targets of the run
+// matching filter, with what's needed to tell whether they're due for kind.
+// Disabled targets never come due, their last run isn't updated and they'd
+// stay due forever, unless the run was asked for one of them by hand.
+func (d *Dependencies) scheduledTargets(ctx context.Context, kind string, filter bson.M) (*m.ScheduleClasses, []m.Target, error) {
+	classes, err := d.scheduleClasses(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter = targetsFilter(ctx, filter)
+	if paramsFrom(ctx).Target == "" {
+		filter["enabled"] = bson.M{"$ne": false}
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "name": 1, "bounty": 1, "schedule": 1, "scanWindow": 1, lastRunFields[kind]: 1})
+	cursor, err := d.db.Collection("targets").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	var targets []m.Target
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, nil, fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	return classes, targets, nil
+}
+
+// dueOnTick reports whether a target matching selector with an interval of
+// its own, or of its class, came due for kind since the last run. A job only
+// runs as often as its own schedule says, which would otherwise cap shorter
+// intervals.
+func (d *Dependencies) dueOnTick(ctx context.Context, kind string, selector m.LabelSelector) (bool, error) {
+	classes, targets, err := d.scheduledTargets(ctx, kind, selector.Filter())
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	for i := range targets {
+		target := &targets[i]
+		if classes.Interval(target, kind) == 0 || !classes.IsDue(target, kind, now) {
+			continue
+		}
+
+		if ok, _ := target.ScanWindow.Open(now); kind == m.ScheduleScanning && !ok {
+			continue
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// markHandled records that the targets of the run, the ones matching selector
+// among them, went through kind. Runs on the assets of events don't count.
+func (d *Dependencies) markHandled(ctx context.Context, kind string, selector m.LabelSelector) {
+	params := paramsFrom(ctx)
+	if len(params.Subdomains) != 0 || len(params.Services) != 0 {
+		return
+	}
+
+	// The run's context may be over already, bookkeeping gets its own.
+	saveCtx, cancel := runsContext()
+	defer cancel()
+
+	filter := bson.M{}
+	ids, err := selectedTargetIDs(withParams(saveCtx, params), d.db, selector)
+	if err == nil && ids != nil {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	if err == nil {
+		update := bson.M{"$set": bson.M{lastRunFields[kind]: time.Now()}}
+		_, err = d.db.Collection("targets").UpdateMany(saveCtx, filter, update)
+	}
+	if err != nil {
+		d.notify.ErrNotif(fmt.Errorf("[!] Error while recording the last %s of targets: %w", kind, err))
+	}
+}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// findFilter returns the filter of the find command sent to collection.
+func findFilter(mt *mtest.T, collection string) bson.Raw {
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		if event.CommandName == "find" && event.Command.Lookup("find").StringValue() == collection {
+			return event.Command.Lookup("filter").Document()
+		}
+	}
+
+	mt.Fatalf("no find was sent to %s", collection)
+	return nil
+}
+
+func TestDueOnTickSkipsDisabled(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	schedules := mtest.CreateCursorResponse(0, "EagleEye.config", mtest.FirstBatch, bson.D{
+		{Key: "schedules", Value: bson.D{{Key: "noBounty", Value: bson.D{{Key: "enumeration", Value: "1h"}}}}},
+	})
+	// A target that never ran, so it's due as soon as it has an interval.
+	target := bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "name", Value: "example"}}
+
+	mt.Run("disabled", func(mt *mtest.T) {
+		d := &Dependencies{db: mt.DB}
+
+		// What the db returns for a filter leaving the disabled target out.
+		mt.AddMockResponses(schedules, mtest.CreateCursorResponse(0, "EagleEye.targets", mtest.FirstBatch))
+
+		due, err := d.dueOnTick(context.Background(), m.ScheduleEnumeration, m.LabelSelector{})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if due {
+			mt.Error("dueOnTick = true for a disabled target")
+		}
+
+		filter := findFilter(mt, "targets")
+		enabled, err := filter.LookupErr("enabled", "$ne")
+		if err != nil || enabled.Boolean() {
+			mt.Errorf("targets filter %s doesn't leave disabled targets out", filter)
+		}
+	})
+
+	mt.Run("enabled", func(mt *mtest.T) {
+		d := &Dependencies{db: mt.DB}
+		mt.AddMockResponses(schedules, mtest.CreateCursorResponse(0, "EagleEye.targets", mtest.FirstBatch, target))
+
+		due, err := d.dueOnTick(context.Background(), m.ScheduleEnumeration, m.LabelSelector{})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if !due {
+			mt.Error("dueOnTick = false for an enabled target that never ran")
+		}
+	})
+
+	mt.Run("asked by hand", func(mt *mtest.T) {
+		d := &Dependencies{db: mt.DB}
+		mt.AddMockResponses(schedules, mtest.CreateCursorResponse(0, "EagleEye.targets", mtest.FirstBatch, target))
+
+		ctx := withParams(context.Background(), RunParams{Target: "example"})
+		if _, _, err := d.scheduledTargets(ctx, m.ScheduleEnumeration, bson.M{}); err != nil {
+			mt.Fatal(err)
+		}
+
+		// A run asked for a disabled target still goes through it.
+		if filter := findFilter(mt, "targets"); filter.Lookup("enabled").Validate() == nil {
+			mt.Errorf("targets filter %s leaves the target asked for out if it's disabled", filter)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// step is a node of a job's graph along with the task carrying it out.
+type step struct {
+	def   m.StepDefinition
+	task  Task
+	retry retryPolicy
+
+	// budget is how long the step may run, see JobDefinition.StepBudgets.
+	budget time.Duration
+}
+
+// stepResult is how a step ended, ran is false for skipped steps.
+type stepResult struct {
+	name  string
+	stats RunStats
+	err   error
+	ran   bool
+}
+
+// shouldRun reports whether the step's condition holds, given the results of
+// the steps it depends on.
+func (s *step) shouldRun(results map[string]stepResult) bool {
+	if s.def.When == m.StepAlways {
+		return true
+	}
+
+	foundNew := false
+	for _, dep := range s.def.DependsOn {
+		result := results[dep]
+		if !result.ran || result.err != nil {
+			return false
+		}
+		foundNew = foundNew || result.stats.New != 0
+	}
+
+	return s.def.When != m.StepOnNew || foundNew || len(s.def.DependsOn) == 0
+}
+
+func (s *step) ready(results map[string]stepResult) bool {
+	for _, dep := range s.def.DependsOn {
+		if _, ok := results[dep]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runSteps walks the job's graph, starting every step as soon as the steps it
+// depends on are done. Steps whose condition doesn't hold are skipped, and so
+// are the ones left once the run is cancelled, timed out or, for scheduled
+// runs, the job got deactivated.
+func (j *job) runSteps(ctx context.Context, run *m.JobRun, trigger string) (RunStats, error) {
+	var total RunStats
+	var errs error
+
+	results := map[string]stepResult{}
+	done := make(chan stepResult)
+	pending := append([]step(nil), j.steps...)
+	running := 0
+
+	for {
+		for progressed := true; progressed; {
+			progressed = false
+			scheduled := trigger == m.TriggerSchedule || trigger == m.TriggerDue
+			stopped := ctx.Err() != nil || scheduled && !j.state.isActive()
+
+			remaining := pending[:0]
+			for _, s := range pending {
+				if !s.ready(results) {
+					remaining = append(remaining, s)
+					continue
+				}
+				progressed = true
+
+				if stopped || !s.shouldRun(results) {
+					log.Printf("[~] %s: skipping step %s.\n", j.def.Name, s.def.Name)
+					results[s.def.Name] = stepResult{name: s.def.Name}
+					continue
+				}
+
+				running++
+				go func(s step) {
+					stats, err := j.runStep(ctx, run, s)
+					done <- stepResult{name: s.def.Name, stats: stats, err: err, ran: true}
+				}(s)
+			}
+			pending = remaining
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-done
+		running--
+		results[result.name] = result
+
+		total.New += result.stats.New
+		total.Skipped = append(total.Skipped, result.stats.Skipped...)
+		total.Failed = append(total.Failed, result.stats.Failed...)
+		errs = errors.Join(errs, result.err)
+	}
+
+	for _, s := range j.steps {
+		if len(s.def.DependsOn) == 0 {
+			total.Input += results[s.def.Name].stats.Input
+		}
+	}
+
+	return total, errs
+}
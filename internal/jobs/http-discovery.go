@@ -2,51 +2,82 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/ArCaneSec/eagleeye/internal/events"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func (h *HttpDiscovery) Start(ctx context.Context, isSubTask bool) {
-	startRegularTask(ctx, h, h.Dependencies.wg, isSubTask)
+func (h *HttpDiscovery) Start(ctx context.Context) (RunStats, error) {
+	return startRegularTask(ctx, h, h.Dependencies.wg)
 }
 
-func (h *HttpDiscoveryAll) Start(ctx context.Context, isSubTask bool) {
-	startRegularTask(ctx, h, h.Dependencies.wg, isSubTask)
+func (h *HttpDiscoveryAll) Start(ctx context.Context) (RunStats, error) {
+	return startRegularTask(ctx, h, h.Dependencies.wg)
 }
 
-func (h *HttpDiscovery) fetchAssets(ctx context.Context) error {
-	cursor, _ := h.db.Collection("http-services").Find(
-		ctx,
-		bson.M{"created": nil},
-	)
+func (h *HttpDiscovery) fetchAssets(ctx context.Context) (int, error) {
+	filter, err := servicesFilter(ctx, h.db, m.LabelSelector{}, bson.M{"created": nil})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, _ := h.db.Collection("http-services").Find(ctx, filter)
 
 	if err := cursor.All(ctx, &h.hosts); err != nil {
-		return fmt.Errorf("[!] Error while fetching new services: %w", err)
+		return 0, fmt.Errorf("[!] Error while fetching new services: %w", err)
 	}
 
-	return nil
+	err = h.excludeOutOfScope(ctx)
+	return len(h.hosts), err
 }
 
-func (t *HttpDiscoveryAll) fetchAssets(ctx context.Context) error {
-	// cursor, _ := t.db.Collection("http-services").Find(ctx, bson.M{})
-	cursor, _ := t.db.Collection("http-services").Find(ctx, bson.M{"host": bson.M{"$regex": ".*buildla.lacity.org.*"}})
+func (t *HttpDiscoveryAll) fetchAssets(ctx context.Context) (int, error) {
+	filter, err := servicesFilter(ctx, t.db, t.selector, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, _ := t.db.Collection("http-services").Find(ctx, filter)
 	if err := cursor.All(ctx, &t.hosts); err != nil {
-		return fmt.Errorf("[!] Error while fetching http services: %w", err)
+		return 0, fmt.Errorf("[!] Error while fetching http services: %w", err)
 	}
 
-	return nil
+	err = t.excludeOutOfScope(ctx)
+	return len(t.hosts), err
+}
+
+func (h *HttpDiscovery) excludeOutOfScope(ctx context.Context) error {
+	guard, err := newScopeGuard(ctx, h.db)
+	if err != nil {
+		return err
+	}
+
+	h.hosts, err = guard.filterServices(ctx, h.hosts)
+	if err != nil {
+		return err
+	}
+
+	// Probed again by the next discovery of everything in their window.
+	h.hosts, _, err = guard.postponeClosed(ctx, h.hosts)
+	return err
 }
 
 func (h *HttpDiscovery) runCommand(ctx context.Context) (string, error) {
+	if h.tool != "" {
+		return h.runProber(ctx)
+	}
+
 	tempFile, httpMap, err := tempFileNServicesMap(h.hosts)
 
 	if err != nil {
@@ -56,12 +87,15 @@ func (h *HttpDiscovery) runCommand(ctx context.Context) (string, error) {
 
 	defer os.Remove(tempFile)
 
-	op, err := execute(ctx,
-		&h.pgid,
+	op, err := h.execute(ctx, &h.procGroup,
 		h.scriptPath,
 		tempFile,
 	)
 
+	h.partial = errors.Is(err, errSoftDeadline)
+	if h.partial {
+		return op, err
+	}
 	if err != nil {
 		return "", fmt.Errorf("[!] Error service discovering subdomains: %w, %s", err, op)
 	}
@@ -69,6 +103,38 @@ func (h *HttpDiscovery) runCommand(ctx context.Context) (string, error) {
 	return op, nil
 }
 
+// runProber is runCommand for tasks running their prober through its
+// adapter.
+func (h *HttpDiscovery) runProber(ctx context.Context) (string, error) {
+	prober, err := loadAdapter[tools.Prober](ctx, h.Dependencies, &h.procGroup, h.tool)
+	if err != nil {
+		return "", err
+	}
+
+	h.httpMap = make(map[string]*m.HttpService, len(h.hosts))
+	hosts := make([]string, 0, len(h.hosts))
+	for i := range h.hosts {
+		h.httpMap[h.hosts[i].HostWithPort()] = &h.hosts[i]
+		hosts = append(hosts, h.hosts[i].HostWithPort())
+	}
+
+	urls, err := prober.Probe(ctx, hosts)
+	h.partial = errors.Is(err, errSoftDeadline)
+	if err != nil && !h.partial {
+		return "", fmt.Errorf("[!] Error service discovering subdomains: %w", err)
+	}
+
+	// Only what was asked for, insertDB looks every line up.
+	known := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if _, hostWithPort := extractHostNUrl(url); h.httpMap[hostWithPort] != nil {
+			known = append(known, url)
+		}
+	}
+
+	return strings.Join(known, "\n"), err
+}
+
 func (t *HttpDiscovery) checkResults(output string) ([]string, error) {
 	resolvedHosts := strings.Split(strings.TrimSpace(output), "\n")
 	if len(resolvedHosts) == 1 && resolvedHosts[0] == "" {
@@ -79,12 +145,13 @@ func (t *HttpDiscovery) checkResults(output string) ([]string, error) {
 	return resolvedHosts, nil
 }
 
-func (t *HttpDiscovery) insertDB(ctx context.Context, results []string) error {
+func (t *HttpDiscovery) insertDB(ctx context.Context, results []string) (int, error) {
 
 	var (
 		now             = time.Now()
 		updates         = make([]mongo.WriteModel, 0, len(t.hosts))
 		newHttpServices = make([]string, 0, len(t.hosts)/2)
+		newServiceIDs   = make([]primitive.ObjectID, 0, len(t.hosts)/2)
 		url             string
 		hostWithPort    string
 		httpObj         *m.HttpService
@@ -99,12 +166,14 @@ func (t *HttpDiscovery) insertDB(ctx context.Context, results []string) error {
 				SetFilter(bson.M{"_id": httpObj.ID}).
 				SetUpdate(bson.M{"$set": bson.M{"host": url, "isActive": true, "created": now, "updated": now}}))
 			newHttpServices = append(newHttpServices, host)
+			newServiceIDs = append(newServiceIDs, httpObj.ID)
 
 			// When http service is created for the first time, host value is schemeless, check dns resolve job.
 			delete(t.httpMap, hostWithPort)
 		} else {
 			if !httpObj.IsActive {
 				newHttpServices = append(newHttpServices, host)
+				newServiceIDs = append(newServiceIDs, httpObj.ID)
 			}
 			updates = append(updates, mongo.NewUpdateOneModel().
 				SetFilter(bson.M{"_id": httpObj.ID}).
@@ -114,6 +183,12 @@ func (t *HttpDiscovery) insertDB(ctx context.Context, results []string) error {
 		}
 	}
 
+	// Services missing from a run stopped at its soft deadline may just not
+	// have been probed, they stay as they are.
+	if t.partial {
+		clear(t.httpMap)
+	}
+
 	for _, notResolvedhost := range t.httpMap {
 		updates = append(updates, mongo.NewUpdateOneModel().
 			SetFilter(bson.M{"_id": notResolvedhost.ID}).
@@ -122,21 +197,14 @@ func (t *HttpDiscovery) insertDB(ctx context.Context, results []string) error {
 
 	_, err := t.db.Collection("http-services").BulkWrite(ctx, updates)
 	if err != nil {
-		return fmt.Errorf("[!] Error while updating http field for new assets: %w", err)
+		return 0, fmt.Errorf("[!] Error while updating http field for new assets: %w", err)
 	}
 
 	if len(newHttpServices) != 0 {
 		log.Printf("[+] Found %d new http services.\n", len(newHttpServices))
 		t.notify.NewHttpNotif(newHttpServices)
+		t.publish(ctx, events.Event{Kind: events.HttpServiceCreated, IDs: newServiceIDs})
 	}
 
-	return nil
-}
-
-func (h *HttpDiscovery) ErrNotif(err error) {
-	h.notify.ErrNotif(err)
-}
-
-func (h *HttpDiscovery) Kill() {
-	syscall.Kill(-h.pgid, syscall.SIGKILL)
+	return len(newHttpServices), nil
 }
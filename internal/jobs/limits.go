@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// slotsFromEnv returns how many jobs may run at once as set by key,
+// MAX_CONCURRENT_JOBS for scheduled and manual runs and MAX_EVENT_JOBS for
+// runs on events, or one by default.
+func slotsFromEnv(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 1
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("[!] Invalid %s %q, running one job at a time.\n", key, value)
+		return 1
+	}
+
+	return n
+}
+
+// toolLimits caps how many instances of a tool run at once across every job
+// and worker, tools are keyed by their file name.
+type toolLimits struct {
+	slots map[string]chan struct{}
+}
+
+// toolLimitsFromEnv reads TOOL_CONCURRENCY, e.g. "enumerate.sh=2,nuclei=1",
+// tools run through adapters go by their binary's name. Tools left out
+// aren't limited.
+func toolLimitsFromEnv() *toolLimits {
+	limits := &toolLimits{slots: map[string]chan struct{}{}}
+
+	for _, entry := range strings.Split(os.Getenv("TOOL_CONCURRENCY"), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(entry, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			log.Printf("[!] Ignoring invalid TOOL_CONCURRENCY entry %q.\n", entry)
+			continue
+		}
+
+		limits.slots[strings.TrimSpace(name)] = make(chan struct{}, n)
+	}
+
+	return limits
+}
+
+// acquire waits for a free slot of command's tool, the returned function
+// gives it back.
+func (t *toolLimits) acquire(ctx context.Context, command string) (func(), error) {
+	slots, ok := t.slots[filepath.Base(command)]
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ArCaneSec/eagleeye/internal/events"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// eventsWait is how long a job collects events before running on them.
+const eventsWait = time.Minute
+
+// listen subscribes the job to the events in its definition, batching them
+// up so a burst of findings ends up in a single run. It returns what stops
+// the job from listening.
+func (j *job) listen(bus *events.Bus) func() {
+	if len(j.def.On) == 0 {
+		return nil
+	}
+
+	var handler events.Handler
+	handler = events.Debounce(eventsWait, func(batch []events.Event) {
+		if !j.state.isActive() {
+			return
+		}
+
+		if err := j.runNow(withParams(context.Background(), eventParams(batch)), m.TriggerEvent); err != nil {
+			// Still busy with the previous run, these go with the next batch.
+			log.Printf("[~] %s is still running, postponing %d events.\n", j.def.Name, len(batch))
+			for _, event := range batch {
+				handler(event)
+			}
+		}
+	})
+
+	unsubscribes := make([]func(), 0, len(j.def.On))
+	for _, kind := range j.def.On {
+		unsubscribes = append(unsubscribes, bus.Subscribe(kind, handler))
+	}
+
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+// eventParams narrows a run down to the assets a batch of events carries.
+func eventParams(batch []events.Event) RunParams {
+	var params RunParams
+
+	for _, event := range batch {
+		switch event.Kind {
+		case events.SubdomainCreated, events.DnsActivated:
+			params.Subdomains = append(params.Subdomains, event.IDs...)
+		case events.HttpServiceCreated:
+			params.Services = append(params.Services, event.IDs...)
+		}
+	}
+
+	return params
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultBackoff    = 30 * time.Second
+	defaultMaxBackoff = 10 * time.Minute
+)
+
+// execError is what execute fails with when the command itself failed.
+type execError struct {
+	err error
+}
+
+func (e *execError) Error() string {
+	return e.err.Error()
+}
+
+func (e *execError) Unwrap() error {
+	return e.err
+}
+
+// errorClass tells which of models.RetryClasses an error belongs to, if any.
+func errorClass(err error) string {
+	var execErr *execError
+	var serverErr mongo.ServerError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err):
+		return "timeout"
+	case errors.As(err, &execErr):
+		return "exec"
+	case mongo.IsNetworkError(err) || errors.As(err, &serverErr):
+		return "db"
+	}
+
+	return ""
+}
+
+type retryPolicy struct {
+	def        *m.RetryPolicy
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	on         map[string]bool
+}
+
+// newRetryPolicy builds a policy from its definition, a nil one never
+// retries.
+func newRetryPolicy(def *m.RetryPolicy) retryPolicy {
+	policy := retryPolicy{def: def, attempts: 1, backoff: defaultBackoff, maxBackoff: defaultMaxBackoff}
+	if def == nil {
+		return policy
+	}
+
+	if def.MaxAttempts > 1 {
+		policy.attempts = def.MaxAttempts
+	}
+	if backoff := def.BackoffDuration(); backoff != 0 {
+		policy.backoff = backoff
+	}
+	if maxBackoff := def.MaxBackoffDuration(); maxBackoff != 0 {
+		policy.maxBackoff = maxBackoff
+	}
+
+	if len(def.On) != 0 {
+		policy.on = map[string]bool{}
+		for _, class := range def.On {
+			policy.on[class] = true
+		}
+	}
+
+	return policy
+}
+
+func (p retryPolicy) retryable(err error) bool {
+	// Past the soft deadline there's only time left to store results.
+	if errors.Is(err, errSoftDeadline) {
+		return false
+	}
+
+	class := errorClass(err)
+	if p.on == nil {
+		return class != ""
+	}
+
+	return p.on[class]
+}
+
+func (p retryPolicy) delay(retry int) time.Duration {
+	delay := p.backoff
+	for i := 1; i < retry && delay < p.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.maxBackoff)
+}
+
+// after is time.After, tests swap it to skip the backoff.
+var after = time.After
+
+// do calls fn until it succeeds, fails with an error the policy doesn't
+// retry, runs out of attempts or ctx is done. It returns how many attempts
+// it took.
+func (p retryPolicy) do(ctx context.Context, name string, fn func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.attempts || ctx.Err() != nil || !p.retryable(err) {
+			return attempt, err
+		}
+
+		delay := p.delay(attempt)
+		log.Printf("[~] %s failed (attempt %d/%d), retrying in %s: %v\n", name, attempt, p.attempts, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-after(delay):
+		}
+	}
+}
+
+// selfRetrying tasks apply their retry policy to every unit of work they're
+// made of, so a failed unit doesn't take the others down with it, instead of
+// being run again as a whole.
+type selfRetrying interface {
+	retriesItself()
+}
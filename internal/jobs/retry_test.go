@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeAfter makes after fire right away, recording the backoff it was asked
+// for. time.After is back once the test is over.
+func fakeAfter(t *testing.T) *[]time.Duration {
+	var delays []time.Duration
+	after = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+	t.Cleanup(func() { after = time.After })
+
+	return &delays
+}
+
+func TestErrorClass(t *testing.T) {
+	exec := &execError{errors.New("exit status 1")}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "plain", err: errors.New("bad output"), want: ""},
+		{name: "deadline", err: context.DeadlineExceeded, want: "timeout"},
+		{name: "wrapped deadline", err: fmt.Errorf("[!] Error while enumerating: %w", context.DeadlineExceeded), want: "timeout"},
+		{name: "exec", err: exec, want: "exec"},
+		{name: "wrapped exec", err: fmt.Errorf("[!] Error while enumerating subdomains: %w, %s", exec, "output"), want: "exec"},
+		{name: "network", err: mongo.CommandError{Labels: []string{"NetworkError"}}, want: "db"},
+		{name: "server", err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, want: "db"},
+		{name: "cancelled", err: context.Canceled, want: ""},
+	}
+
+	for _, test := range tests {
+		if got := errorClass(test.err); got != test.want {
+			t.Errorf("%s: errorClass = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := newRetryPolicy(&m.RetryPolicy{MaxAttempts: 8, Backoff: "1s", MaxBackoff: "5s"})
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if got := policy.delay(i + 1); got != delay {
+			t.Errorf("delay(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+
+	// Defaults, and a backoff over the cap.
+	defaults := newRetryPolicy(&m.RetryPolicy{MaxAttempts: 3})
+	if got := defaults.delay(1); got != defaultBackoff {
+		t.Errorf("default delay(1) = %s, want %s", got, defaultBackoff)
+	}
+	if got := defaults.delay(100); got != defaultMaxBackoff {
+		t.Errorf("default delay(100) = %s, want %s", got, defaultMaxBackoff)
+	}
+
+	capped := newRetryPolicy(&m.RetryPolicy{MaxAttempts: 3, Backoff: "1h", MaxBackoff: "1m"})
+	if got := capped.delay(1); got != time.Minute {
+		t.Errorf("capped delay(1) = %s, want 1m", got)
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	exec := &execError{errors.New("exit status 1")}
+
+	tests := []struct {
+		name     string
+		def      *m.RetryPolicy
+		errs     []error
+		attempts int
+		delays   []time.Duration
+		fails    bool
+	}{
+		{name: "no policy", def: nil, errs: []error{exec}, attempts: 1, fails: true},
+		{name: "succeeds first", def: &m.RetryPolicy{MaxAttempts: 3}, errs: []error{nil}, attempts: 1},
+		{
+			name:     "backoff grows",
+			def:      &m.RetryPolicy{MaxAttempts: 4, Backoff: "1s", MaxBackoff: "1m"},
+			errs:     []error{exec, exec, exec, nil},
+			attempts: 4,
+			delays:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:     "backoff capped",
+			def:      &m.RetryPolicy{MaxAttempts: 5, Backoff: "10s", MaxBackoff: "25s"},
+			errs:     []error{exec, exec, exec, exec, exec},
+			attempts: 5,
+			delays:   []time.Duration{10 * time.Second, 20 * time.Second, 25 * time.Second, 25 * time.Second},
+			fails:    true,
+		},
+		{
+			name:     "unclassified error",
+			def:      &m.RetryPolicy{MaxAttempts: 3},
+			errs:     []error{errors.New("bad output")},
+			attempts: 1,
+			fails:    true,
+		},
+		{
+			name:     "class left out",
+			def:      &m.RetryPolicy{MaxAttempts: 3, On: []string{"db", "timeout"}},
+			errs:     []error{exec},
+			attempts: 1,
+			fails:    true,
+		},
+		{
+			name:     "class listed",
+			def:      &m.RetryPolicy{MaxAttempts: 3, Backoff: "1s", On: []string{"exec"}},
+			errs:     []error{exec, nil},
+			attempts: 2,
+			delays:   []time.Duration{time.Second},
+		},
+		{
+			name:     "class changes",
+			def:      &m.RetryPolicy{MaxAttempts: 3, Backoff: "1s", On: []string{"timeout"}},
+			errs:     []error{context.DeadlineExceeded, exec},
+			attempts: 2,
+			delays:   []time.Duration{time.Second},
+			fails:    true,
+		},
+		{
+			name:     "soft deadline",
+			def:      &m.RetryPolicy{MaxAttempts: 3},
+			errs:     []error{fmt.Errorf("%w: %v", errSoftDeadline, exec)},
+			attempts: 1,
+			fails:    true,
+		},
+	}
+
+	for _, test := range tests {
+		delays := fakeAfter(t)
+
+		calls := 0
+		attempts, err := newRetryPolicy(test.def).do(context.Background(), test.name, func() error {
+			calls++
+			return test.errs[calls-1]
+		})
+
+		if attempts != test.attempts || calls != test.attempts {
+			t.Errorf("%s: %d attempts, %d calls, want %d", test.name, attempts, calls, test.attempts)
+		}
+		if (err != nil) != test.fails {
+			t.Errorf("%s: do = %v, want failure %t", test.name, err, test.fails)
+		}
+		if !slices.Equal(*delays, test.delays) {
+			t.Errorf("%s: backed off %v, want %v", test.name, *delays, test.delays)
+		}
+	}
+}
+
+func TestRetryDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	exec := &execError{errors.New("exit status 1")}
+	policy := newRetryPolicy(&m.RetryPolicy{MaxAttempts: 5, Backoff: "1h"})
+
+	// Cancelled while backing off, with the real clock.
+	time.AfterFunc(10*time.Millisecond, cancel)
+	attempts, err := policy.do(ctx, "test", func() error { return exec })
+	if attempts != 1 || !errors.Is(err, exec) {
+		t.Errorf("do = %d, %v, want 1 attempt failing with %v", attempts, err, exec)
+	}
+
+	// Already cancelled when the attempt failed.
+	calls := 0
+	attempts, _ = policy.do(ctx, "test", func() error {
+		calls++
+		return exec
+	})
+	if attempts != 1 || calls != 1 {
+		t.Errorf("do on a cancelled context made %d attempts, want 1", calls)
+	}
+}
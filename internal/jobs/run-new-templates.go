@@ -2,66 +2,154 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"syscall"
+	"strings"
+
+	"github.com/ArCaneSec/eagleeye/internal/queue"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (r *RunNewTemplates) Start(ctx context.Context, isSubTask bool) {
+func (r *RunNewTemplates) Start(ctx context.Context) (RunStats, error) {
 	r.wg.Add(1)
 	defer r.wg.Done()
 
 	log.Println("[*] RunNewTemplates started...")
-	var err error
+	var stats RunStats
 
 	templatesPath, err := r.fetchConfig(ctx)
 	if err != nil {
-		r.notify.ErrNotif(err)
-		return
+		return stats, err
+	}
+
+	ctx, due, err := r.narrowToDue(ctx, m.ScheduleScanning)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := r.postponeTemplates(ctx, templatesPath, paramsFrom(ctx).postponed); err != nil {
+		return stats, err
+	}
+
+	// Templates owed to targets whose window was closed go first.
+	found, errs := r.scanPending(ctx)
+	stats.New += found
+
+	if !due {
+		return stats, errs
+	}
+
+	hosts, postponed, err := r.fetchAssets(ctx)
+	if err != nil {
+		return stats, errors.Join(errs, err)
 	}
+	stats.Input = len(hosts)
 
-	hosts, err := r.fetchAssets(ctx)
+	if err := r.postponeTemplates(ctx, templatesPath, postponed); err != nil {
+		return stats, errors.Join(errs, err)
+	}
+
+	found, err = r.scanHosts(ctx, templatesPath, nil, hosts)
+	stats.New += found
 	if err != nil {
-		r.notify.ErrNotif(err)
-		return
+		return stats, errors.Join(errs, err)
 	}
 
+	r.markHandled(ctx, m.ScheduleScanning, r.selector)
+	log.Println("[*] RunNewTemplates finished.")
+	return stats, errs
+}
+
+// scanHosts runs templates on hosts in chunks, on the workers if there are
+// any. The templates are either listed in the file at templatesPath or, for
+// the ones owed to a target, passed in themselves.
+func (r *RunNewTemplates) scanHosts(ctx context.Context, templatesPath string, templates []string, hosts []string) (int, error) {
 	// Breaking data into small chunks so we can scan all safety
 	MAX_CHUNKS := 10000
+	var chunks [][]string
 	for len(hosts) > 0 {
 		if MAX_CHUNKS > len(hosts) {
 			MAX_CHUNKS = len(hosts)
 		}
-		chunks := hosts[:MAX_CHUNKS]
+		chunks = append(chunks, hosts[:MAX_CHUNKS])
 		hosts = hosts[MAX_CHUNKS:]
+	}
+
+	if len(chunks) == 0 {
+		return 0, nil
+	}
 
-		output, err := r.runCommand(ctx, templatesPath, chunks)
+	if r.queue != nil {
+		payloads := make([]any, 0, len(chunks))
+		for _, chunk := range chunks {
+			payloads = append(payloads, nucleiPayload{Hosts: chunk, Templates: templatesPath, TemplateList: templates, ScriptPath: r.scriptPath, Tool: r.tool})
+		}
+
+		units, errs := r.dispatch(ctx, "nuclei-chunk", payloads)
+
+		var found int
+		for i, unit := range units {
+			found += unit.New
+			if unit.Status == queue.StatusFailed {
+				errs = errors.Join(errs, fmt.Errorf("[!] Scanning chunk %d failed: %s", i, unit.Error))
+			}
+		}
+
+		return found, errs
+	}
+
+	if templates != nil {
+		listPath, err := writeList(templates)
 		if err != nil {
-			r.notify.ErrNotif(err)
-			return
+			return 0, err
 		}
+		defer os.Remove(listPath)
+		templatesPath = listPath
+	}
 
-		results, err := checkResults(output)
+	var found int
+	for _, chunk := range chunks {
+		n, err := r.scanChunk(ctx, templatesPath, chunk)
+		found += n
 		if err != nil {
-			if _, ok := err.(ErrNoResult); ok {
-				continue
-			}
+			return found, err
+		}
+	}
+
+	return found, nil
+}
+
+// scanChunk runs the new templates against hosts and reports what it found.
+func (r *RunNewTemplates) scanChunk(ctx context.Context, templatesPath string, hosts []string) (int, error) {
+	// Findings of a scan stopped at the soft deadline are still reported.
+	output, stopped := r.runCommand(ctx, templatesPath, hosts)
+	if stopped != nil && (!errors.Is(stopped, errSoftDeadline) || output == "") {
+		return 0, stopped
+	}
 
-			r.notify.ErrNotif(err)
-			return
+	results, err := checkResults(output)
+	if err != nil {
+		if _, ok := err.(ErrNoResult); ok {
+			return 0, stopped
 		}
 
-		r.wg.Add(1)
-		go func() {
-			defer r.wg.Done()
-			r.notify.NucleiResultsNotif(results)
-		}()
+		return 0, err
 	}
-	log.Println("[*] RunNewTemplates finished.")
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.notify.NucleiResultsNotif(results)
+	}()
+
+	return strings.Count(results, "\n") + 1, stopped
 }
 
 func (r *RunNewTemplates) fetchConfig(ctx context.Context) (string, error) {
@@ -76,28 +164,54 @@ func (r *RunNewTemplates) fetchConfig(ctx context.Context) (string, error) {
 	return res["nucleiUpdatePath"].(string), nil
 }
 
-func (r *RunNewTemplates) fetchAssets(ctx context.Context) ([]string, error) {
-	opts := options.Find().SetProjection(bson.M{"_id": 0, "host": 1})
+// fetchAssets returns the hosts to scan and the targets whose hosts were
+// postponed by their scan window.
+func (r *RunNewTemplates) fetchAssets(ctx context.Context) ([]string, []primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "subdomain": 1, "host": 1, "excluded": 1})
+
+	filter, err := servicesFilter(ctx, r.db, r.selector, bson.M{"isActive": true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cursor, err := r.db.Collection("http-services").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[!] Error fetching assets from db: %w", err)
+	}
+
+	var services []m.HttpService
+	if err = cursor.All(ctx, &services); err != nil {
+		return nil, nil, fmt.Errorf("[!] Error deserializing hosts from db: %w", err)
+	}
+
+	guard, err := newScopeGuard(ctx, r.db)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	cursor, err := r.db.Collection("http-services").Find(ctx, bson.M{"isActive": true}, opts)
+	services, err = guard.filterServices(ctx, services)
 	if err != nil {
-		return nil, fmt.Errorf("[!] Error fetching assets from db: %w", err)
+		return nil, nil, err
 	}
 
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
-		return nil, fmt.Errorf("[!] Error deserializing hosts from db: %w", err)
+	services, postponed, err := guard.postponeClosed(ctx, services)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	hosts := make([]string, 0, len(results))
-	for _, hostMap := range results {
-		hosts = append(hosts, hostMap["host"].(string))
+	hosts := make([]string, 0, len(services))
+	for _, service := range services {
+		hosts = append(hosts, service.Host)
 	}
 
-	return hosts, nil
+	return hosts, postponed, nil
 }
 
 func (r *RunNewTemplates) runCommand(ctx context.Context, tmplPath string, hosts []string) (string, error) {
+	if r.tool != "" {
+		return r.runScanner(ctx, tmplPath, hosts)
+	}
+
 	tempFile, err := os.CreateTemp("/tmp/", "hosts")
 	if err != nil {
 		return "", fmt.Errorf("[!] Error creating temp file: %w", err)
@@ -109,7 +223,10 @@ func (r *RunNewTemplates) runCommand(ctx context.Context, tmplPath string, hosts
 		tempFile.WriteString(fmt.Sprintf("%s\n", host))
 	}
 
-	results, err := execute(ctx, &r.pgid, r.scriptPath, tempFile.Name(), tmplPath)
+	results, err := r.execute(ctx, &r.procGroup, r.scriptPath, tempFile.Name(), tmplPath)
+	if errors.Is(err, errSoftDeadline) {
+		return results, err
+	}
 	if err != nil {
 		return "", fmt.Errorf("[!] Error while executing new templates script: %w, %s", err, results)
 	}
@@ -117,6 +234,18 @@ func (r *RunNewTemplates) runCommand(ctx context.Context, tmplPath string, hosts
 	return results, nil
 }
 
-func (r *RunNewTemplates) Kill() {
-	syscall.Kill(-r.pgid, syscall.SIGKILL)
+// runScanner is runCommand for tasks running their scanner through its
+// adapter.
+func (r *RunNewTemplates) runScanner(ctx context.Context, tmplPath string, hosts []string) (string, error) {
+	scanner, err := loadAdapter[tools.Scanner](ctx, r.Dependencies, &r.procGroup, r.tool)
+	if err != nil {
+		return "", err
+	}
+
+	findings, err := scanner.Scan(ctx, hosts, tmplPath)
+	if err != nil && !errors.Is(err, errSoftDeadline) {
+		return "", fmt.Errorf("[!] Error while running new templates: %w", err)
+	}
+
+	return strings.Join(findings, "\n"), err
 }
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Runs are written with their own context, a run that hit its deadline still
+// has to be recorded.
+func runsContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (d *Dependencies) startRun(runCtx context.Context, jobID primitive.ObjectID, parent *m.JobRun, task string) *m.JobRun {
+	params := paramsFrom(runCtx)
+	run := &m.JobRun{
+		Job:     jobID,
+		Task:    task,
+		Trigger: params.trigger,
+		Target:  params.Target,
+		Status:  m.RunRunning,
+		Started: time.Now(),
+	}
+	if parent != nil {
+		run.Parent = &parent.ID
+	}
+
+	ctx, cancel := runsContext()
+	defer cancel()
+
+	rs, err := d.db.Collection("job_runs").InsertOne(ctx, run)
+	if err != nil {
+		log.Printf("[!] Error while recording run of %s: %v\n", task, err)
+		return run
+	}
+	run.ID = rs.InsertedID.(primitive.ObjectID)
+
+	return run
+}
+
+func (d *Dependencies) finishRun(run *m.JobRun, stats RunStats, err error) {
+	now := time.Now()
+
+	run.Finished = &now
+	run.Input, run.New, run.Skipped, run.Failed = stats.Input, stats.New, stats.Skipped, stats.Failed
+	run.Status = runStatus(err)
+	if err != nil && run.Status != m.RunCancelled {
+		run.Error = err.Error()
+	}
+
+	if run.ID.IsZero() {
+		return
+	}
+
+	ctx, cancel := runsContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"status":   run.Status,
+		"error":    run.Error,
+		"attempts": run.Attempts,
+		"skipped":  run.Skipped,
+		"failed":   run.Failed,
+		"input":    run.Input,
+		"new":      run.New,
+		"finished": run.Finished,
+	}}
+	if _, err := d.db.Collection("job_runs").UpdateByID(ctx, run.ID, update); err != nil {
+		log.Printf("[!] Error while recording run of %s: %v\n", run.Task, err)
+	}
+}
+
+// JobRuns returns the latest runs of a job, newest first, each with the runs
+// of its tasks.
+func (s *Scheduler) JobRuns(ctx context.Context, id string, limit int64) ([]m.JobRun, error) {
+	job, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := s.deps.db.Collection("job_runs")
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"job": job.def.ID, "parent": nil}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching runs of %s: %w", job.def.Name, err)
+	}
+
+	runs := []m.JobRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("error while fetching runs of %s: %w", job.def.Name, err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(runs))
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+
+	cursor, err = collection.Find(ctx, bson.M{"parent": bson.M{"$in": ids}}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching task runs of %s: %w", job.def.Name, err)
+	}
+
+	var taskRuns []m.JobRun
+	if err := cursor.All(ctx, &taskRuns); err != nil {
+		return nil, fmt.Errorf("error while fetching task runs of %s: %w", job.def.Name, err)
+	}
+
+	byParent := map[primitive.ObjectID][]m.JobRun{}
+	for _, taskRun := range taskRuns {
+		byParent[*taskRun.Parent] = append(byParent[*taskRun.Parent], taskRun)
+	}
+	for i := range runs {
+		runs[i].Tasks = byParent[runs[i].ID]
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// postponeClosed drops the services of targets whose scan window is closed
+// and returns the ids of those targets. The services are left untouched, so
+// the next run inside the window picks them up.
+func (g *scopeGuard) postponeClosed(ctx context.Context, services []m.HttpService) ([]m.HttpService, []primitive.ObjectID, error) {
+	owners, err := g.serviceOwners(ctx, services)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	open := make([]m.HttpService, 0, len(services))
+	postponed := map[primitive.ObjectID]int{}
+	reasons := map[primitive.ObjectID]string{}
+
+	for _, service := range services {
+		target := g.targets[owners[service.Subdomain]]
+		if target == nil {
+			open = append(open, service)
+			continue
+		}
+
+		if ok, reason := target.ScanWindow.Open(now); !ok {
+			postponed[target.ID]++
+			reasons[target.ID] = reason
+			continue
+		}
+		open = append(open, service)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(postponed))
+	for id, count := range postponed {
+		log.Printf("[~] Postponing %d http services of %s, %s.\n", count, g.targets[id].Name, reasons[id])
+		ids = append(ids, id)
+	}
+
+	return open, ids, nil
+}
+
+// pendingTemplates are the new templates a target's closed scan window kept
+// from running on it. They're stored in the "pending-templates" collection
+// until a run finds the window open, the file they were listed in is
+// overwritten by the next nuclei update.
+type pendingTemplates struct {
+	Target    primitive.ObjectID `bson:"target"`
+	Templates []string           `bson:"templates"`
+}
+
+// postponeTemplates records that the templates listed in templatesPath are
+// owed to targets.
+func (r *RunNewTemplates) postponeTemplates(ctx context.Context, templatesPath string, targets []primitive.ObjectID) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	templates, err := readList(templatesPath)
+	if err != nil || len(templates) == 0 {
+		return err
+	}
+
+	updates := make([]mongo.WriteModel, 0, len(targets))
+	for _, id := range targets {
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"target": id}).
+			SetUpdate(bson.M{"$addToSet": bson.M{"templates": bson.M{"$each": templates}}}).
+			SetUpsert(true))
+	}
+
+	if _, err := r.db.Collection("pending-templates").BulkWrite(ctx, updates); err != nil {
+		return fmt.Errorf("[!] Error while recording postponed templates: %w", err)
+	}
+
+	log.Printf("[~] %d templates are pending for %d targets.\n", len(templates), len(targets))
+	return nil
+}
+
+// scanPending runs the templates owed to the targets of the run whose scan
+// window is open again. Runs on the assets of events leave them for the next
+// run on everything.
+func (r *RunNewTemplates) scanPending(ctx context.Context) (int, error) {
+	params := paramsFrom(ctx)
+	if len(params.Subdomains) != 0 || len(params.Services) != 0 {
+		return 0, nil
+	}
+
+	cursor, err := r.db.Collection("pending-templates").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("[!] Error while fetching pending templates: %w", err)
+	}
+
+	var pending []pendingTemplates
+	if err := cursor.All(ctx, &pending); err != nil {
+		return 0, fmt.Errorf("[!] Error while fetching pending templates: %w", err)
+	}
+
+	var found int
+	var errs error
+	now := time.Now()
+
+	for _, batch := range pending {
+		var target m.Target
+		opts := options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "scanWindow": 1})
+		err := r.db.Collection("targets").FindOne(ctx, bson.M{"_id": batch.Target}, opts).Decode(&target)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			errs = errors.Join(errs, r.dropPending(batch))
+			continue
+		}
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("[!] Error while fetching target of pending templates: %w", err))
+			continue
+		}
+
+		if ok, _ := target.ScanWindow.Open(now); !ok {
+			continue
+		}
+
+		// Only this target, and only if the run covers it.
+		narrowed := params
+		narrowed.due = []primitive.ObjectID{target.ID}
+		targetCtx := withParams(ctx, narrowed)
+
+		covered, err := selectedTargetIDs(targetCtx, r.db, r.selector)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if len(covered) == 0 {
+			continue
+		}
+
+		hosts, _, err := r.fetchAssets(targetCtx)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		log.Printf("[*] Running %d pending templates on %d hosts of %s.\n", len(batch.Templates), len(hosts), target.Name)
+		n, err := r.scanHosts(targetCtx, "", batch.Templates, hosts)
+		found += n
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		errs = errors.Join(errs, r.dropPending(batch))
+	}
+
+	return found, errs
+}
+
+// dropPending removes the templates of batch from the ones its target is
+// owed, keeping any a newer run added meanwhile.
+func (r *RunNewTemplates) dropPending(batch pendingTemplates) error {
+	// The run's context may be over already, bookkeeping gets its own.
+	saveCtx, cancel := runsContext()
+	defer cancel()
+
+	pending := r.db.Collection("pending-templates")
+	filter := bson.M{"target": batch.Target}
+
+	_, err := pending.UpdateOne(saveCtx, filter, bson.M{"$pullAll": bson.M{"templates": batch.Templates}})
+	if err == nil {
+		filter["templates"] = bson.M{"$size": 0}
+		_, err = pending.DeleteOne(saveCtx, filter)
+	}
+	if err != nil {
+		return fmt.Errorf("[!] Error while dropping pending templates: %w", err)
+	}
+
+	return nil
+}
+
+// readList returns the non empty lines of the file at path.
+func readList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while reading %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[!] Error while reading %s: %w", path, err)
+	}
+
+	return lines, nil
+}
+
+// writeList writes lines to a temp file and returns its path, it's up to the
+// caller to remove it.
+func writeList(lines []string) (string, error) {
+	tempFile, err := os.CreateTemp("/tmp/", "list")
+	if err != nil {
+		return "", fmt.Errorf("[!] Error creating temp file: %w", err)
+	}
+	defer tempFile.Close()
+
+	if _, err := tempFile.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("[!] Error writing temp file: %w", err)
+	}
+
+	return tempFile.Name(), nil
+}
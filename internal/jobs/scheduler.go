@@ -3,74 +3,291 @@ package jobs
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/ArCaneSec/eagleeye/internal/events"
 	"github.com/ArCaneSec/eagleeye/internal/notifs"
+	"github.com/ArCaneSec/eagleeye/internal/queue"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
 
 	"github.com/go-co-op/gocron/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type job struct {
-	duration  time.Duration
-	cronJob   gocron.Job
-	task      Task
-	active    bool
-	cDuration time.Duration
-	killer    context.CancelFunc
-	isRunning bool
-	subTasks  []Task
+	def      m.JobDefinition
+	deps     *Dependencies
+	duration time.Duration
+	steps    []step
+
+	state *runState
 }
 
+// killGrace is how long a cancelled run gets to stop through its context
+// before its process group is killed.
+const killGrace = 15 * time.Second
+
+// errCancelled marks a run that was cancelled by hand rather than failed.
+var errCancelled = errors.New("run cancelled")
+
+// schedule translates the job's definition to its gocron counterpart.
+func (j *job) schedule() gocron.JobDefinition {
+	switch {
+	case j.def.Cron != "":
+		fields := strings.Fields(j.def.Cron)
+		if len(fields) != 0 && strings.HasPrefix(fields[0], "CRON_TZ=") {
+			fields = fields[1:]
+		}
+		return gocron.CronJob(j.def.Cron, len(fields) == 6)
+
+	case len(j.def.DailyAt) != 0:
+		atTimes := make([]gocron.AtTime, 0, len(j.def.DailyAt))
+		for _, at := range j.def.DailyAt {
+			t, _ := m.ParseDailyTime(at)
+			atTimes = append(atTimes, gocron.NewAtTime(t.Hours, t.Minutes, t.Seconds))
+		}
+		return gocron.DailyJob(1, gocron.NewAtTimes(atTimes[0], atTimes[1:]...))
+	}
+
+	return gocron.DurationJob(j.duration)
+}
+
+// sleep is time.Sleep, tests swap it to see the delays.
+var sleep = time.Sleep
+
+// waitJitter delays a run by a random part of the job's jitter, it returns
+// false if the job got deactivated meanwhile.
+func (j *job) waitJitter() bool {
+	jitter := j.def.JitterDuration()
+	if jitter <= 0 {
+		return true
+	}
+
+	delay := time.Duration(rand.Int63n(int64(jitter)))
+	log.Printf("[~] Delaying %s by %s.\n", j.def.Name, delay.Round(time.Second))
+	sleep(delay)
+
+	return j.state.isActive()
+}
+
+// runTask is what gocron calls on every scheduled run.
 func (j *job) runTask() {
-	if !j.active {
+	if !j.state.isActive() || !j.waitJitter() {
 		return
 	}
 
-	j.isRunning = true
-	defer func() {
-		j.isRunning = false
-	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := j.state.begin(cancel); err != nil {
+		cancel()
+		log.Printf("[~] %s is still running, skipping this run.\n", j.def.Name)
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), j.cDuration)
-	j.killer = cancel
+	j.run(ctx, cancel, m.TriggerSchedule)
+}
 
-	if j.subTasks != nil {
-		j.task.Start(ctx, true)
-		for _, task := range j.subTasks {
-			if !j.active {
-				return
-			}
-			task.Start(ctx, true)
-		}
+// runNow starts a run right away without touching the job's schedule. The
+// context carries the RunParams narrowing the run down, if any.
+func (j *job) runNow(parent context.Context, trigger string) error {
+	ctx, cancel := context.WithCancel(parent)
+	if err := j.state.begin(cancel); err != nil {
+		cancel()
+		return fmt.Errorf("job %s is already running", j.def.Name)
+	}
+
+	go j.run(ctx, cancel, trigger)
+	return nil
+}
+
+// run carries out a run the job's state has already been claimed for.
+func (j *job) run(parent context.Context, cancel context.CancelFunc, trigger string) {
+	defer cancel()
+
+	params := paramsFrom(parent)
+	params.trigger = trigger
+	parent = withParams(parent, params)
+
+	run := j.deps.startRun(parent, j.def.ID, nil, j.name())
+	defer j.state.end(run)
+
+	slots := j.deps.slots
+	if trigger == m.TriggerEvent {
+		slots = j.deps.eventSlots
+	}
+
+	select {
+	case slots <- struct{}{}:
+		defer func() {
+			<-slots
+		}()
+	case <-parent.Done():
+		j.deps.finishRun(run, RunStats{}, errCancelled)
 		return
 	}
-	j.task.Start(ctx, false)
+
+	// Every step gets its own budget rather than sharing a single timeout, so
+	// a slow one can't leave the steps after it without time.
+	total, errs := j.runSteps(parent, run, trigger)
+	j.deps.finishRun(run, total, errs)
+}
+
+// name is what the job's runs are recorded as, its main task for jobs that
+// have one.
+func (j *job) name() string {
+	if j.def.Type != "" {
+		return j.def.Type
+	}
+
+	return j.def.Name
 }
 
-func execute(ctx context.Context, pgid *int, command string, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, command, args...)
+// runStep runs one of the job's tasks and records it as part of run.
+func (j *job) runStep(ctx context.Context, run *m.JobRun, s step) (RunStats, error) {
+	name, task := s.def.Name, s.task
+	taskRun := j.deps.startRun(ctx, j.def.ID, run, name)
+	started := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, s.budget)
+	defer cancel()
+	ctx = withSoftDeadline(ctx)
+
+	params := paramsFrom(ctx)
+	params.job, params.step, params.run = j.def.ID, name, taskRun.ID
+	ctx = withParams(ctx, params)
+
+	j.state.startTask(name, task)
+	defer j.state.endTask(name)
+
+	var stats RunStats
+	var err error
+
+	if _, ok := task.(selfRetrying); ok {
+		stats, err = task.Start(ctx)
+	} else {
+		taskRun.Attempts, err = s.retry.do(ctx, name, func() error {
+			var err error
+			stats, err = task.Start(ctx)
+			return err
+		})
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = errCancelled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, errSoftDeadline):
+		err = fmt.Errorf("%w after %s: %v", errTimedOut, time.Since(started).Round(time.Second), err)
+		j.deps.notify.ErrNotif(err)
+	case err != nil:
+		j.deps.notify.ErrNotif(err)
+	}
+
+	j.deps.finishRun(taskRun, stats, err)
+	return stats, err
+}
+
+// cancel stops the current run through its context, killing the running
+// task's process group if it's still around after killGrace. The job's
+// schedule stays as it is.
+func (j *job) cancel() error {
+	tasks, done, ok := j.state.stop()
+	if !ok {
+		return fmt.Errorf("job %s is not running", j.def.Name)
+	}
+
+	go j.killAfterGrace(tasks, done)
+	return nil
+}
+
+// stopRun cancels the current run, if any, and waits for it to end.
+func (j *job) stopRun() {
+	if tasks, done, ok := j.state.stop(); ok {
+		j.killAfterGrace(tasks, done)
+	}
+}
+
+// killAfterGrace waits for a stopped run to end, killing the process groups
+// of its tasks if it's still around after killGrace.
+func (j *job) killAfterGrace(tasks []Task, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-time.After(killGrace):
+	}
+
+	log.Printf("[~] %s didn't stop in %s, killing it.\n", j.def.Name, killGrace)
+	for _, task := range tasks {
+		task.Kill()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		log.Printf("[!] %s is still running after being killed.\n", j.def.Name)
+	}
+}
+
+// execute runs command in its own process group. Commands still running at
+// the soft deadline of their step are stopped and return what they printed
+// so far along with errSoftDeadline. Failed commands return their stderr, or
+// everything they printed when ctx asks for it with tools.WithStderr.
+func (d *Dependencies) execute(ctx context.Context, pg *procGroup, command string, args ...string) (string, error) {
+	work, cancelWork := workContext(ctx)
+	defer cancelWork()
+
+	release, err := d.tools.acquire(work, command)
+	if err == nil {
+		defer release()
+		err = work.Err()
+	}
+	if err != nil {
+		if ctx.Err() == nil && work.Err() != nil {
+			return "", fmt.Errorf("%w: %v", errSoftDeadline, err)
+		}
+		return "", err
+	}
+
+	cmd := exec.CommandContext(work, command, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	errOutput := &stderr
+	if tools.WantsStderr(ctx) {
+		cmd.Stderr = &stdout
+		errOutput = &stdout
+	}
 
 	if err := cmd.Start(); err != nil {
-		return stderr.String(), err
+		return errOutput.String(), &execError{err}
 	}
 
 	id, _ := syscall.Getpgid(cmd.Process.Pid)
-	*pgid = id
+	pg.add(id)
+	defer pg.remove(id)
 
 	if err := cmd.Wait(); err != nil {
-		return stderr.String(), err
+		switch {
+		case ctx.Err() != nil:
+			return errOutput.String(), fmt.Errorf("%w: %v", ctx.Err(), err)
+		case work.Err() != nil:
+			return stdout.String(), fmt.Errorf("%w: %v", errSoftDeadline, err)
+		}
+		return errOutput.String(), &execError{err}
 	}
 
 	return stdout.String(), nil
@@ -78,191 +295,350 @@ func execute(ctx context.Context, pgid *int, command string, args ...string) (st
 
 type Scheduler struct {
 	core gocron.Scheduler
-	jobs []*job
+	jobs map[string]*job
+	deps *Dependencies
 	wg   *sync.WaitGroup
+	mu   sync.Mutex
+}
+
+func (s *Scheduler) lookup(id string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("invalid id: %s", id)
+	}
+
+	return job, nil
 }
 
-func (s *Scheduler) KillJob(id int) error {
-	if id+1 > len(s.jobs) {
-		return fmt.Errorf("invalid id: %d", id)
+func (s *Scheduler) KillJob(id string) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
 	}
 
-	job := s.jobs[id]
+	s.deactivate(job)
 
-	s.core.RemoveJob(job.cronJob.ID())
-	job.active = false
-	job.killer()
-	job.task.Kill()
+	tasks, _, _ := job.state.stop()
+	for _, task := range tasks {
+		task.Kill()
+	}
 
 	return nil
 }
 
-func (s *Scheduler) DeactiveJob(id int) error {
-	if id+1 > len(s.jobs) {
-		return fmt.Errorf("invalid id: %d", id)
+// CancelJob cancels the current run of a job, it keeps being scheduled.
+func (s *Scheduler) CancelJob(id string) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	return job.cancel()
+}
+
+// DeactiveJob stops scheduling a job and remembers it, so it stays inactive
+// after a restart as well.
+func (s *Scheduler) DeactiveJob(id string) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if !job.state.isActive() {
+		return fmt.Errorf("job id %s is already inactive", id)
+	}
+
+	s.deactivate(job)
+	return s.saveActive(job, false)
+}
+
+// ActiveJob starts scheduling a job and remembers it, so it stays active
+// after a restart as well.
+func (s *Scheduler) ActiveJob(id string) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if job.state.isActive() {
+		return fmt.Errorf("job id %s is already active", id)
+	}
+
+	if err := s.activate(job); err != nil {
+		return err
+	}
+	return s.saveActive(job, true)
+}
+
+// RunNow triggers a single run of a job, its schedule stays as it is.
+func (s *Scheduler) RunNow(id string, params RunParams) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
 	}
 
-	job := s.jobs[id]
-	if !job.active {
-		return fmt.Errorf("job id %d is already inactive", id)
+	return job.runNow(withParams(context.Background(), params), m.TriggerManual)
+}
+
+func (s *Scheduler) activate(job *job) error {
+	var cronJob gocron.Job
+
+	if job.def.HasSchedule() {
+		options := []gocron.JobOption{}
+		if job.def.StartsImmediately() {
+			options = append(options, gocron.WithStartAt(gocron.WithStartImmediately()))
+		}
+
+		options = append(options, gocron.WithSingletonMode(gocron.LimitModeReschedule))
+
+		var err error
+		cronJob, err = s.core.NewJob(job.schedule(), gocron.NewTask(job.runTask), options...)
+		if err != nil {
+			return fmt.Errorf("error while scheduling job %s: %w", job.def.Name, err)
+		}
 	}
 
-	s.core.RemoveJob(job.cronJob.ID())
-	job.active = false
+	job.state.schedule(cronJob, job.listen(s.deps.events))
 
 	return nil
 }
 
-func (s *Scheduler) ActiveJob(id int) error {
-	if id+1 > len(s.jobs) {
-		return fmt.Errorf("invalid id: %d", id)
+func (s *Scheduler) deactivate(job *job) {
+	cronJob, unsubscribe := job.state.unschedule()
+	if cronJob != nil {
+		s.core.RemoveJob(cronJob.ID())
 	}
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
 
-	job := s.jobs[id]
-	if job.active {
-		return fmt.Errorf("job id %d is already active", id)
+func (s *Scheduler) saveActive(job *job, active bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"active": active}}
+	if _, err := s.deps.db.Collection("jobs").UpdateByID(ctx, job.def.ID, update); err != nil {
+		return fmt.Errorf("error while saving job %s: %w", job.def.Name, err)
 	}
+	s.mu.Lock()
+	job.def.Active = active
+	s.mu.Unlock()
 
-	j, _ := s.core.NewJob(
-		gocron.DurationJob(job.duration),
-		gocron.NewTask(job.runTask),
-		gocron.WithStartAt(gocron.WithStartImmediately()),
-	)
+	return nil
+}
 
-	job.cronJob = j
-	job.active = true
+// Definitions returns the definitions of every job, oldest first.
+func (s *Scheduler) Definitions() []m.JobDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defs := make([]m.JobDefinition, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		defs = append(defs, job.def)
+	}
+	sort.Slice(defs, func(i, k int) bool {
+		return defs[i].ID.Hex() < defs[k].ID.Hex()
+	})
 
+	return defs
+}
+
+// CreateJob stores a new job definition and schedules it if it's active.
+func (s *Scheduler) CreateJob(ctx context.Context, def m.JobDefinition) (*m.JobDefinition, error) {
+	job, err := buildJob(s.deps, def)
+	if err != nil {
+		return nil, err
+	}
+
+	def.ID = primitive.NilObjectID
+	rs, err := s.deps.db.Collection("jobs").InsertOne(ctx, def)
+	if err != nil {
+		return nil, fmt.Errorf("error while saving job %s: %w", def.Name, err)
+	}
+	job.def.ID = rs.InsertedID.(primitive.ObjectID)
+
+	s.register(job)
+	return &job.def, nil
+}
+
+// UpdateJob replaces a job's definition. A run already in progress finishes
+// with the old definition, the next one uses the new definition. The new job
+// takes the old one's state over, so the run in progress can still be
+// cancelled or killed and no other run starts alongside it.
+func (s *Scheduler) UpdateJob(ctx context.Context, id string, def m.JobDefinition) (*m.JobDefinition, error) {
+	old, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	def.ID = old.def.ID
+	job, err := buildJob(s.deps, def)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.deps.db.Collection("jobs").ReplaceOne(ctx, bson.M{"_id": def.ID}, def); err != nil {
+		return nil, fmt.Errorf("error while saving job %s: %w", def.Name, err)
+	}
+
+	s.deactivate(old)
+	job.state = old.state
+
+	s.register(job)
+	return &job.def, nil
+}
+
+// DeleteJob unschedules a job and removes its definition, a run in progress
+// is cancelled and waited for.
+func (s *Scheduler) DeleteJob(ctx context.Context, id string) error {
+	job, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.deps.db.Collection("jobs").DeleteOne(ctx, bson.M{"_id": job.def.ID}); err != nil {
+		return fmt.Errorf("error while deleting job %s: %w", job.def.Name, err)
+	}
+
+	s.deactivate(job)
+
+	s.mu.Lock()
+	delete(s.jobs, id)
+	s.mu.Unlock()
+
+	job.stopRun()
 	return nil
 }
 
-func (s *Scheduler) Shutdown() error {
-	for id, job := range s.jobs {
-		if !job.active {
+func (s *Scheduler) register(job *job) {
+	s.mu.Lock()
+	s.jobs[job.def.ID.Hex()] = job
+	s.mu.Unlock()
+
+	if job.def.Active {
+		if err := s.activate(job); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// watchDue runs the active jobs that have targets due between their own runs,
+// checking every dueTick.
+func (s *Scheduler) watchDue() {
+	ticker := time.NewTicker(dueTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		jobs := make([]*job, 0, len(s.jobs))
+		for _, job := range s.jobs {
+			jobs = append(jobs, job)
+		}
+		s.mu.Unlock()
+
+		for _, job := range jobs {
+			if job.state.isActive() {
+				job.runIfDue()
+			}
+		}
+	}
+}
+
+// runIfDue starts a run if one of the job's steps has targets due.
+func (j *job) runIfDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, s := range j.steps {
+		kind, ok := scheduleKinds[s.def.Type]
+		if !ok {
+			continue
+		}
+
+		// Validated with the definition already.
+		selector, _ := m.ParseLabelSelector(s.def.Selector)
+
+		due, err := j.deps.dueOnTick(ctx, kind, selector)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if !due {
 			continue
 		}
 
-		err := s.DeactiveJob(id)
+		// Busy running already, the next tick checks again.
+		if err := j.runNow(context.Background(), m.TriggerDue); err == nil {
+			log.Printf("[*] Running %s for targets due for %s.\n", j.def.Name, kind)
+		}
+		return
+	}
+}
+
+func (s *Scheduler) Shutdown() error {
+	for _, def := range s.Definitions() {
+		job, err := s.lookup(def.ID.Hex())
 		if err != nil {
-			return fmt.Errorf("error while shutting scheduler down: %w", err)
+			continue
 		}
+
+		s.deactivate(job)
 	}
 	return nil
 }
 
 func (s *Scheduler) KillAll() {
-	for id := range s.jobs {
-		s.KillJob(id)
+	for _, def := range s.Definitions() {
+		s.KillJob(def.ID.Hex())
 	}
 }
 
-func ScheduleJobs(db *mongo.Database, wg *sync.WaitGroup) *Scheduler {
-	s, _ := gocron.NewScheduler(gocron.WithLimitConcurrentJobs(1, gocron.LimitModeWait))
-	notifier := notifs.NewNotif(os.Getenv("DISCORD_WEBHOOK"))
-	deps := &Dependencies{
-		db:     db,
-		notify: notifier,
-		wg:     wg,
+func newDependencies(db *mongo.Database, wg *sync.WaitGroup) *Dependencies {
+	return &Dependencies{
+		db:         db,
+		notify:     notifs.NewNotif(os.Getenv("DISCORD_WEBHOOK")),
+		wg:         wg,
+		events:     events.NewBus(),
+		slots:      make(chan struct{}, slotsFromEnv("MAX_CONCURRENT_JOBS")),
+		eventSlots: make(chan struct{}, slotsFromEnv("MAX_EVENT_JOBS")),
+		tools:      toolLimitsFromEnv(),
 	}
+}
 
-	jobs := []*job{
-		subdomainEnumerationJob(deps),
-		// dnsResolveAllJob(deps),
-		// httpDiscoveryAllJob(deps),
-		// updateNucleiJob(deps),
-		// runNewTemplatesJob(deps),
+// ScheduleJobs loads and schedules every job. With distributed set, tasks
+// that split their work hand it to workers through the queue instead of
+// running it themselves.
+func ScheduleJobs(db *mongo.Database, wg *sync.WaitGroup, distributed bool) *Scheduler {
+	s, _ := gocron.NewScheduler()
+	deps := newDependencies(db, wg)
+	if distributed {
+		deps.queue = queue.New(db)
 	}
 
-	scheduler := &Scheduler{s, jobs, wg}
+	scheduler := &Scheduler{core: s, jobs: map[string]*job{}, deps: deps, wg: wg}
+
+	defs, err := loadDefinitions(db)
+	if err != nil {
+		log.Fatalf("[!] Error while loading job definitions, err: %v", err)
+	}
 
 	s.Start()
-	for id := range scheduler.jobs {
-		scheduler.ActiveJob(id)
+	for _, def := range defs {
+		job, err := buildJob(deps, def)
+		if err != nil {
+			log.Printf("[!] Skipping job %s: %v\n", def.Name, err)
+			continue
+		}
+
+		scheduler.register(job)
 		time.Sleep(5 * time.Millisecond)
 	}
 
+	go scheduler.watchDue()
+
 	return scheduler
 }
-
-func dnsResolveAllJob(d *Dependencies) *job {
-	return &job{
-		duration: 48 * time.Hour,
-		task: &DnsResolveAll{
-			&DnsResolve{
-				Dependencies: d,
-				scriptPath:   "/home/arcane/automation/resolve.sh",
-			},
-		},
-		cDuration: 2 * time.Hour,
-	}
-}
-
-func subdomainEnumerationJob(d *Dependencies) *job {
-	return &job{
-		duration: 48 * time.Hour,
-		task: &SubdomainEnumeration{
-			Dependencies: d,
-			scriptPath:   "/home/arcane/tools/eagleeye/scripts/enumerate.sh",
-		},
-		cDuration: 2 * time.Hour,
-		subTasks: []Task{
-			&DnsResolve{
-				Dependencies: d,
-				scriptPath:   "/home/arcane/automation/resolve.sh",
-			},
-			&HttpDiscovery{
-				Dependencies: d,
-				scriptPath:   "/home/arcane/automation/discovery.sh",
-			},
-		},
-	}
-}
-
-func httpDiscoveryAllJob(d *Dependencies) *job {
-	return &job{
-		duration: 48 * time.Hour,
-		task: &HttpDiscoveryAll{
-			HttpDiscovery: &HttpDiscovery{
-				Dependencies: d,
-				scriptPath:   "/home/arcane/automation/discovery.sh",
-			},
-		},
-		cDuration: 2 * time.Hour,
-	}
-}
-
-func updateNucleiJob(d *Dependencies) *job {
-	return &job{
-		duration: 24 * time.Hour,
-		task: &UpdateNuclei{
-			Dependencies: d,
-			scriptPath:   "/home/arcane/tools/eagleeye/scripts/update-nuclei.sh",
-		},
-		cDuration: 2 * time.Hour,
-		subTasks: []Task{
-			&RunNewTemplates{
-				Dependencies: d,
-				scriptPath:   "/home/arcane/tools/eagleeye/scripts/nuclei-new-templates.sh",
-			}},
-	}
-}
-
-func runNewTemplatesJob(d *Dependencies) *job {
-	return &job{
-		duration: 24 * time.Hour,
-		task: &RunNewTemplates{
-			Dependencies: d,
-			scriptPath:   "/home/arcane/tools/eagleeye/scripts/nuclei-new-templates.sh",
-		},
-		cDuration: 2 * time.Hour,
-	}
-}
-
-// func testDiscordJob(d *Dependencies) *job {
-// 	return &job{
-// 		duration: 1 * time.Hour,
-// 		task: &TestDiscord{
-// 			Dependencies: d,
-// 		},
-// 		cDuration:  2 * time.Hour,
-// 	}
-// }
@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"github.com/go-co-op/gocron/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fakeSleep makes sleep record its delays instead of waiting, calling fn on
+// each of them if it's set. time.Sleep is back once the test is over.
+func fakeSleep(t *testing.T, fn func(time.Duration)) *[]time.Duration {
+	var delays []time.Duration
+	sleep = func(d time.Duration) {
+		delays = append(delays, d)
+		if fn != nil {
+			fn(d)
+		}
+	}
+	t.Cleanup(func() { sleep = time.Sleep })
+
+	return &delays
+}
+
+func TestWaitJitter(t *testing.T) {
+	for _, jitter := range []string{"", "0s"} {
+		delays := fakeSleep(t, nil)
+		j := &job{def: m.JobDefinition{Name: "test", Jitter: jitter}, state: newRunState()}
+
+		if !j.waitJitter() {
+			t.Errorf("waitJitter with jitter %q = false, want true", jitter)
+		}
+		if len(*delays) != 0 {
+			t.Errorf("waitJitter with jitter %q slept %v", jitter, *delays)
+		}
+	}
+
+	delays := fakeSleep(t, nil)
+	j := &job{def: m.JobDefinition{Name: "test", Jitter: "10m"}, state: newRunState()}
+	j.state.schedule(nil, nil)
+
+	for i := 0; i < 100; i++ {
+		if !j.waitJitter() {
+			t.Fatal("waitJitter of an active job = false, want true")
+		}
+	}
+
+	for _, delay := range *delays {
+		if delay < 0 || delay >= 10*time.Minute {
+			t.Fatalf("waitJitter slept %s, want within [0, 10m)", delay)
+		}
+	}
+	if len(*delays) != 100 {
+		t.Errorf("waitJitter slept %d times, want 100", len(*delays))
+	}
+
+	// Deactivated while it waited.
+	fakeSleep(t, func(time.Duration) { j.state.unschedule() })
+	if j.waitJitter() {
+		t.Error("waitJitter of a job deactivated meanwhile = true, want false")
+	}
+}
+
+func TestJobSchedule(t *testing.T) {
+	tests := []struct {
+		name string
+		def  m.JobDefinition
+	}{
+		{name: "interval", def: m.JobDefinition{Interval: "48h"}},
+		{name: "cron", def: m.JobDefinition{Cron: "0 3 * * *"}},
+		{name: "cron with seconds", def: m.JobDefinition{Cron: "30 0 3 * * *"}},
+		{name: "cron timezone", def: m.JobDefinition{Cron: "CRON_TZ=Asia/Tehran 0 3 * * *"}},
+		{name: "cron timezone with seconds", def: m.JobDefinition{Cron: "CRON_TZ=UTC 30 0 3 * * *"}},
+		{name: "daily", def: m.JobDefinition{DailyAt: []string{"02:30"}}},
+		{name: "daily twice", def: m.JobDefinition{DailyAt: []string{"02:30", "14:30:15"}}},
+	}
+
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+
+	for _, test := range tests {
+		j := &job{def: test.def, duration: 48 * time.Hour}
+		if _, err := s.NewJob(j.schedule(), gocron.NewTask(func() {})); err != nil {
+			t.Errorf("%s: gocron rejected the schedule: %v", test.name, err)
+		}
+	}
+}
+
+// runningJob registers an inactive job with a run in progress, the run ends
+// as soon as it's cancelled.
+func runningJob(t *testing.T, s *Scheduler) (*job, context.Context) {
+	def := m.JobDefinition{Name: "test", TaskDefinition: m.TaskDefinition{Type: "update-nuclei"}, Interval: "1h", Timeout: "1h"}
+	def.ID = primitive.NewObjectID()
+
+	j, err := buildJob(s.deps, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.register(j)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := j.state.begin(cancel); err != nil {
+		t.Fatal(err)
+	}
+	j.state.startTask("update-nuclei", j.steps[0].task)
+
+	go func() {
+		<-ctx.Done()
+		j.state.end(&m.JobRun{Task: "test", Status: m.RunCancelled})
+	}()
+
+	return j, ctx
+}
+
+func TestUpdateJobKeepsRun(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("update", func(mt *mtest.T) {
+		s := &Scheduler{jobs: map[string]*job{}, deps: &Dependencies{db: mt.DB}}
+		old, ctx := runningJob(mt.T, s)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		def := old.def
+		def.Interval = "2h"
+		if _, err := s.UpdateJob(context.Background(), old.def.ID.Hex(), def); err != nil {
+			mt.Fatal(err)
+		}
+
+		updated, err := s.lookup(old.def.ID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if updated.def.Interval != "2h" {
+			mt.Fatalf("interval = %s after the update, want 2h", updated.def.Interval)
+		}
+		if got := updated.state.snapshot().State; got != m.RunRunning {
+			mt.Errorf("updated job is %s, want the run in progress kept", got)
+		}
+		if err := updated.runNow(context.Background(), m.TriggerManual); err == nil {
+			mt.Error("a second run started alongside the one in progress")
+		}
+
+		if err := s.CancelJob(old.def.ID.Hex()); err != nil {
+			mt.Fatalf("the run in progress can't be cancelled after the update: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			mt.Fatal("cancelling the updated job didn't reach the run in progress")
+		}
+	})
+}
+
+func TestDeleteJobStopsRun(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("delete", func(mt *mtest.T) {
+		s := &Scheduler{jobs: map[string]*job{}, deps: &Dependencies{db: mt.DB}}
+		j, ctx := runningJob(mt.T, s)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		if err := s.DeleteJob(context.Background(), j.def.ID.Hex()); err != nil {
+			mt.Fatal(err)
+		}
+
+		// DeleteJob returns once the run is over.
+		if ctx.Err() == nil {
+			mt.Error("the run in progress wasn't cancelled")
+		}
+		if got := j.state.snapshot().State; got != m.RunCancelled {
+			mt.Errorf("deleted job is %s, want its run ended", got)
+		}
+		if _, err := s.lookup(j.def.ID.Hex()); err == nil {
+			mt.Error("the deleted job can still be looked up")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ArCaneSec/eagleeye/internal/history"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *ScopeSync) Start(ctx context.Context) (RunStats, error) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	log.Println("[*] ScopeSync started...")
+
+	if err := s.fetchAssets(ctx); err != nil {
+		return RunStats{}, err
+	}
+
+	stats := RunStats{Input: len(s.targets)}
+	for _, target := range s.targets {
+		select {
+		case <-ctx.Done():
+			return stats, fmt.Errorf("[!] Context deadline exceeds in scope sync job")
+		default:
+		}
+
+		changed, err := s.syncTarget(ctx, target)
+		if err != nil {
+			s.notify.ErrNotif(err)
+		}
+		if changed {
+			stats.New++
+		}
+	}
+
+	log.Println("[#] ScopeSync finished.")
+	return stats, nil
+}
+
+func (s *ScopeSync) fetchAssets(ctx context.Context) error {
+	filter := bson.M{
+		"source": bson.M{"$in": []string{"hackerone", "bugcrowd", "integrity", "yeswehack"}},
+		"handle": bson.M{"$nin": []any{nil, ""}},
+	}
+
+	cursor, err := s.db.Collection("targets").Find(ctx, targetsFilter(ctx, filter))
+	if err != nil {
+		return fmt.Errorf("[!] Error while fetching targets to sync: %w", err)
+	}
+
+	if err := cursor.All(ctx, &s.targets); err != nil {
+		return fmt.Errorf("[!] Error while fetching targets to sync: %w", err)
+	}
+
+	return nil
+}
+
+// syncTarget updates target with its current scope on its platform and
+// reports whether anything changed.
+func (s *ScopeSync) syncTarget(ctx context.Context, target m.Target) (bool, error) {
+	program, err := s.client.FetchProgram(ctx, target.Source, target.Handle)
+	if err != nil {
+		return false, fmt.Errorf("[!] Error while fetching scope of %s from %s: %w", target.Name, target.Source, err)
+	}
+
+	// An empty scope is far more likely to be an API hiccup than a program
+	// dropping everything, keep what we have.
+	if len(program.Scope) == 0 {
+		return false, fmt.Errorf("[!] %s returned an empty scope for %s, ignoring it", target.Source, target.Name)
+	}
+
+	if _, err := m.NewScopeMatcher(program.Scope, program.OutOfScope); err != nil {
+		return false, fmt.Errorf("[!] %s returned an invalid scope for %s: %w", target.Source, target.Name, err)
+	}
+
+	addedIn, removedIn := m.DiffScope(target.Scope, program.Scope)
+	addedOut, removedOut := m.DiffScope(target.OutOfScope, program.OutOfScope)
+
+	if len(addedIn)+len(removedIn)+len(addedOut)+len(removedOut) == 0 {
+		return false, nil
+	}
+
+	// What the scope was before the sync is worth keeping.
+	if err := history.Seed(ctx, s.db, &target); err != nil {
+		return false, err
+	}
+
+	update := bson.M{"$set": bson.M{"scope": program.Scope, "outOfScope": program.OutOfScope}}
+	if _, err := s.db.Collection("targets").UpdateOne(ctx, bson.M{"_id": target.ID}, update); err != nil {
+		return false, fmt.Errorf("[!] Error while updating scope of %s: %w", target.Name, err)
+	}
+
+	target.Scope, target.OutOfScope = program.Scope, program.OutOfScope
+	_, recordErr := history.Record(ctx, s.db, &target, "sync")
+
+	added := append(addedIn, outOfScopeLabels(addedOut)...)
+	removed := append(removedIn, outOfScopeLabels(removedOut)...)
+
+	log.Printf("[+] Scope of %s changed, %d added and %d removed.\n", target.Name, len(added), len(removed))
+	s.notify.ScopeChangeNotif(target.Name, added, removed)
+
+	return true, recordErr
+}
+
+func outOfScopeLabels(entries []string) []string {
+	labels := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		labels = append(labels, fmt.Sprintf("%s (out of scope)", entry))
+	}
+
+	return labels
+}
+
+func (s *ScopeSync) Kill() {}
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scopeGuard drops assets its target's ScopeMatcher rejects before they reach
+// any tool and keeps the excluded marker of every checked asset in sync.
+type scopeGuard struct {
+	db      *mongo.Database
+	targets map[primitive.ObjectID]*m.Target
+}
+
+func newScopeGuard(ctx context.Context, db *mongo.Database) (*scopeGuard, error) {
+	cursor, err := db.Collection("targets").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching targets for scope check: %w", err)
+	}
+
+	var targets []m.Target
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching targets for scope check: %w", err)
+	}
+
+	g := &scopeGuard{db: db, targets: make(map[primitive.ObjectID]*m.Target, len(targets))}
+	for i := range targets {
+		g.targets[targets[i].ID] = &targets[i]
+	}
+
+	return g, nil
+}
+
+// newExclusion returns the excluded marker for host, or nil if target allows it.
+func newExclusion(target *m.Target, host string) *m.Exclusion {
+	if target == nil {
+		return &m.Exclusion{Reason: "target not found", Created: time.Now()}
+	}
+
+	matcher, err := target.Matcher()
+	if err != nil {
+		return &m.Exclusion{Reason: fmt.Sprintf("invalid scope of %s: %s", target.Name, err), Created: time.Now()}
+	}
+
+	if ok, reason := matcher.Check(host); !ok {
+		return &m.Exclusion{Reason: fmt.Sprintf("%s of %s", reason, target.Name), Created: time.Now()}
+	}
+
+	return nil
+}
+
+// exclusionUpdate returns the write needed to bring the stored marker of a
+// document in line with the fresh result, or nil if nothing changed.
+func exclusionUpdate(id primitive.ObjectID, stored, fresh *m.Exclusion) mongo.WriteModel {
+	switch {
+	case fresh != nil && stored == nil:
+		return mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"excluded": fresh}})
+	case fresh == nil && stored != nil:
+		return mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$unset": bson.M{"excluded": ""}})
+	}
+
+	return nil
+}
+
+func (g *scopeGuard) filterSubdomains(ctx context.Context, subs []m.Subdomain) ([]m.Subdomain, error) {
+	inScope := make([]m.Subdomain, 0, len(subs))
+	updates := make([]mongo.WriteModel, 0)
+
+	for _, sub := range subs {
+		exclusion := newExclusion(g.targets[sub.Target], sub.Subdomain)
+		if update := exclusionUpdate(sub.ID, sub.Excluded, exclusion); update != nil {
+			updates = append(updates, update)
+		}
+
+		if exclusion != nil {
+			continue
+		}
+		sub.Excluded = nil
+		inScope = append(inScope, sub)
+	}
+
+	if err := g.save(ctx, "subdomains", updates); err != nil {
+		return nil, err
+	}
+
+	if skipped := len(subs) - len(inScope); skipped != 0 {
+		log.Printf("[~] Skipped %d out of scope subdomains.\n", skipped)
+	}
+
+	return inScope, nil
+}
+
+func (g *scopeGuard) filterServices(ctx context.Context, services []m.HttpService) ([]m.HttpService, error) {
+	owners, err := g.serviceOwners(ctx, services)
+	if err != nil {
+		return nil, err
+	}
+
+	inScope := make([]m.HttpService, 0, len(services))
+	updates := make([]mongo.WriteModel, 0)
+
+	for _, service := range services {
+		exclusion := newExclusion(g.targets[owners[service.Subdomain]], service.Host)
+		if update := exclusionUpdate(service.ID, service.Excluded, exclusion); update != nil {
+			updates = append(updates, update)
+		}
+
+		if exclusion != nil {
+			continue
+		}
+		service.Excluded = nil
+		inScope = append(inScope, service)
+	}
+
+	if err := g.save(ctx, "http-services", updates); err != nil {
+		return nil, err
+	}
+
+	if skipped := len(services) - len(inScope); skipped != 0 {
+		log.Printf("[~] Skipped %d out of scope http services.\n", skipped)
+	}
+
+	return inScope, nil
+}
+
+// serviceOwners maps the subdomain of every service to the target owning it.
+func (g *scopeGuard) serviceOwners(ctx context.Context, services []m.HttpService) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(services))
+	for _, service := range services {
+		ids = append(ids, service.Subdomain)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "target": 1})
+	cursor, err := g.db.Collection("subdomains").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching subdomains of http services: %w", err)
+	}
+
+	var subs []m.Subdomain
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, fmt.Errorf("[!] Error while fetching subdomains of http services: %w", err)
+	}
+
+	owners := make(map[primitive.ObjectID]primitive.ObjectID, len(subs))
+	for _, sub := range subs {
+		owners[sub.ID] = sub.Target
+	}
+
+	return owners, nil
+}
+
+func (g *scopeGuard) save(ctx context.Context, collection string, updates []mongo.WriteModel) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if _, err := g.db.Collection(collection).BulkWrite(ctx, updates); err != nil {
+		return fmt.Errorf("[!] Error while updating excluded markers of %s: %w", collection, err)
+	}
+
+	return nil
+}
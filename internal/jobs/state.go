@@ -0,0 +1,233 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// A job starts out pending, moves to running for every run and from there to
+// the state its run ended in. Any finished state can start a new run.
+var transitions = map[string][]string{
+	m.RunPending:   {m.RunRunning},
+	m.RunRunning:   {m.RunSucceeded, m.RunFailed, m.RunCancelled, m.RunTimedOut},
+	m.RunSucceeded: {m.RunRunning},
+	m.RunFailed:    {m.RunRunning},
+	m.RunCancelled: {m.RunRunning},
+	m.RunTimedOut:  {m.RunRunning},
+}
+
+// errTimedOut marks a run that hit its timeout.
+var errTimedOut = errors.New("run timed out")
+
+// runStatus maps the error a run ended with to the state it ended in.
+func runStatus(err error) string {
+	switch {
+	case err == nil:
+		return m.RunSucceeded
+	case errors.Is(err, errCancelled):
+		return m.RunCancelled
+	case errors.Is(err, errTimedOut):
+		return m.RunTimedOut
+	}
+
+	return m.RunFailed
+}
+
+// runState is everything about a job that changes while it's scheduled and
+// run, it's shared between gocron, manual runs and the API so every field is
+// guarded by mu.
+type runState struct {
+	mu sync.Mutex
+
+	state       string
+	active      bool
+	cronJob     gocron.Job
+	unsubscribe func()
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	tasks   map[string]Task
+	lastRun *m.JobRun
+}
+
+// stateSnapshot is a consistent copy of a job's runState.
+type stateSnapshot struct {
+	State       string
+	Active      bool
+	CronJob     gocron.Job
+	CurrentTask string
+	LastRun     *m.JobRun
+}
+
+func newRunState() *runState {
+	return &runState{state: m.RunPending}
+}
+
+func (s *runState) transition(to string) error {
+	for _, next := range transitions[s.state] {
+		if next == to {
+			s.state = to
+			return nil
+		}
+	}
+
+	return fmt.Errorf("can't go from %s to %s", s.state, to)
+}
+
+// begin claims the job for a new run, it fails if a run is in progress.
+func (s *runState) begin(cancel context.CancelFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.transition(m.RunRunning); err != nil {
+		return err
+	}
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	return nil
+}
+
+// end releases the job once its run is over.
+func (s *runState) end(run *m.JobRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.transition(run.Status); err != nil {
+		// Whatever went wrong, the job mustn't stay running for good.
+		to := run.Status
+		if !slices.Contains(transitions[m.RunRunning], to) {
+			to = m.RunFailed
+		}
+		log.Printf("[!] Ending run of %s: %v, moving to %s.\n", run.Task, err, to)
+		s.state = to
+	}
+	s.lastRun = run
+	s.cancel, s.tasks = nil, nil
+	close(s.done)
+}
+
+// startTask and endTask keep track of the steps running right now.
+func (s *runState) startTask(name string, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tasks == nil {
+		s.tasks = map[string]Task{}
+	}
+	s.tasks[name] = task
+}
+
+func (s *runState) endTask(name string) {
+	s.mu.Lock()
+	delete(s.tasks, name)
+	s.mu.Unlock()
+}
+
+// stop cancels the current run and returns the tasks it was running, along
+// with a channel closed once the run is over.
+func (s *runState) stop() ([]Task, <-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != m.RunRunning {
+		return nil, nil, false
+	}
+	s.cancel()
+
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+
+	return tasks, s.done, true
+}
+
+func (s *runState) isActive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.active
+}
+
+// schedule marks the job active, along with its gocron counterpart (nil for
+// jobs running only on events) and what stops it from listening to events.
+func (s *runState) schedule(cronJob gocron.Job, unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cronJob, s.unsubscribe, s.active = cronJob, unsubscribe, true
+}
+
+// unschedule marks the job inactive and returns what schedule was given.
+func (s *runState) unschedule() (gocron.Job, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cronJob, unsubscribe := s.cronJob, s.unsubscribe
+	s.cronJob, s.unsubscribe, s.active = nil, nil, false
+
+	return cronJob, unsubscribe
+}
+
+func (s *runState) snapshot() stateSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.tasks))
+	for name := range s.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return stateSnapshot{
+		State:       s.state,
+		Active:      s.active,
+		CronJob:     s.cronJob,
+		CurrentTask: strings.Join(names, ", "),
+		LastRun:     s.lastRun,
+	}
+}
+
+// procGroup tracks the process groups of the commands a task is running, so
+// killing a task never reaches another task's processes.
+type procGroup struct {
+	mu    sync.Mutex
+	pgids map[int]struct{}
+}
+
+func (p *procGroup) add(pgid int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pgids == nil {
+		p.pgids = map[int]struct{}{}
+	}
+	p.pgids[pgid] = struct{}{}
+}
+
+func (p *procGroup) remove(pgid int) {
+	p.mu.Lock()
+	delete(p.pgids, pgid)
+	p.mu.Unlock()
+}
+
+func (p *procGroup) Kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for pgid := range p.pgids {
+		syscall.Kill(-pgid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+func TestRunStateConcurrent(t *testing.T) {
+	state := newRunState()
+
+	for round := 0; round < 20; round++ {
+		var begun atomic.Int32
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if state.begin(func() {}) == nil {
+					begun.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := begun.Load(); n != 1 {
+			t.Fatalf("round %d: %d runs began, want 1", round, n)
+		}
+
+		for i := 0; i < 8; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				state.startTask("step", nil)
+				state.endTask("step")
+			}()
+			go func() {
+				defer wg.Done()
+				state.snapshot()
+			}()
+			go func() {
+				defer wg.Done()
+				state.isActive()
+			}()
+		}
+		wg.Wait()
+
+		_, done, ok := state.stop()
+		if !ok {
+			t.Fatalf("round %d: stop failed on a running job", round)
+		}
+
+		go state.end(&m.JobRun{Task: "test", Status: runStatus(errCancelled)})
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("round %d: run never ended", round)
+		}
+
+		if got := state.snapshot().State; got != m.RunCancelled {
+			t.Fatalf("round %d: state = %s, want %s", round, got, m.RunCancelled)
+		}
+	}
+}
+
+func TestRunStateEndForcesTerminal(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: m.RunSucceeded, want: m.RunSucceeded},
+		{status: m.RunTimedOut, want: m.RunTimedOut},
+		// A run that never got to record how it ended.
+		{status: m.RunRunning, want: m.RunFailed},
+		{status: "", want: m.RunFailed},
+	}
+
+	for _, test := range tests {
+		state := newRunState()
+		if err := state.begin(func() {}); err != nil {
+			t.Fatal(err)
+		}
+
+		state.end(&m.JobRun{Task: "test", Status: test.status})
+		if got := state.snapshot().State; got != test.want {
+			t.Errorf("end(%q) left the job %s, want %s", test.status, got, test.want)
+		}
+
+		if err := state.begin(func() {}); err != nil {
+			t.Errorf("begin after end(%q) failed: %v", test.status, err)
+		}
+	}
+}
+
+func TestProcGroupConcurrent(t *testing.T) {
+	var group procGroup
+	var cmds []*exec.Cmd
+
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command("sleep", "30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			t.Skipf("can't start sleep: %v", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	var wg sync.WaitGroup
+	for _, cmd := range cmds {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			group.add(cmd.Process.Pid)
+		}()
+		go func() {
+			defer wg.Done()
+			// Removing and adding back a group that's in use.
+			group.add(cmd.Process.Pid)
+			group.remove(cmd.Process.Pid)
+			group.add(cmd.Process.Pid)
+		}()
+	}
+	wg.Wait()
+
+	group.Kill()
+
+	for _, cmd := range cmds {
+		waited := make(chan error, 1)
+		go func() { waited <- cmd.Wait() }()
+
+		select {
+		case err := <-waited:
+			var exit *exec.ExitError
+			if !errors.As(err, &exit) {
+				t.Errorf("sleep %d exited with %v, want it killed", cmd.Process.Pid, err)
+			}
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+			t.Errorf("sleep %d survived Kill", cmd.Process.Pid)
+		}
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: m.RunSucceeded},
+		{err: errCancelled, want: m.RunCancelled},
+		{err: errors.Join(errors.New("step failed"), errTimedOut), want: m.RunTimedOut},
+		{err: context.DeadlineExceeded, want: m.RunFailed},
+	}
+
+	for _, test := range tests {
+		if got := runStatus(test.err); got != test.want {
+			t.Errorf("runStatus(%v) = %s, want %s", test.err, got, test.want)
+		}
+	}
+}
+
+// alive reports whether pid is still running, zombies waiting to be reaped
+// don't count.
+func alive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) != 0 && fields[0] != "Z"
+}
+
+func TestKillReapsEveryDomain(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "enumerate.sh")
+	// Like the real script, the work is done by a child of the shell.
+	body := "#!/bin/sh\nsleep 30 &\necho $$ $! > \"" + dir + "/$1.pids\"\nwait\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	task := &SubdomainEnumeration{
+		Dependencies: &Dependencies{tools: &toolLimits{slots: map[string]chan struct{}{"enumerate.sh": make(chan struct{}, 2)}}},
+		scriptPath:   script,
+	}
+
+	domains := []string{"a.example.com", "b.example.com"}
+	errs := make(chan error, len(domains))
+	for _, domain := range domains {
+		go func() {
+			_, err := task.runCommand(context.Background(), domain)
+			errs <- err
+		}()
+	}
+
+	var pids []int
+	for _, domain := range domains {
+		var shell, child int
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			content, _ := os.ReadFile(filepath.Join(dir, domain+".pids"))
+			if _, err := fmt.Sscan(string(content), &shell, &child); err == nil {
+				break
+			}
+			if time.Since(start) > 5*time.Second {
+				task.Kill()
+				t.Fatalf("the script never started for %s", domain)
+			}
+		}
+		pids = append(pids, shell, child)
+	}
+
+	task.Kill()
+
+	for range domains {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("a killed domain returned no error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a domain kept running after Kill")
+		}
+	}
+
+	for _, pid := range pids {
+		for start := time.Now(); alive(pid); time.Sleep(10 * time.Millisecond) {
+			if time.Since(start) > 5*time.Second {
+				syscall.Kill(pid, syscall.SIGKILL)
+				t.Errorf("process %d survived Kill", pid)
+				break
+			}
+		}
+	}
+
+	task.procGroup.mu.Lock()
+	defer task.procGroup.mu.Unlock()
+	if len(task.procGroup.pgids) != 0 {
+		t.Errorf("process groups %v are still tracked after their commands ended", task.procGroup.pgids)
+	}
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobStatus is a job's definition along with what it's doing right now.
+type JobStatus struct {
+	m.JobDefinition
+	State       string     `json:"state"`
+	IsRunning   bool       `json:"isRunning"`
+	CurrentTask string     `json:"currentTask,omitempty"`
+	NextRun     *time.Time `json:"nextRun"`
+	LastRun     *m.JobRun  `json:"lastRun"`
+}
+
+func (s *Scheduler) JobStatus(ctx context.Context, id string) (*JobStatus, error) {
+	job, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	def := job.def
+	s.mu.Unlock()
+
+	state := job.state.snapshot()
+	status := &JobStatus{
+		JobDefinition: def,
+		State:         state.State,
+		IsRunning:     state.State == m.RunRunning,
+		CurrentTask:   state.CurrentTask,
+		LastRun:       state.LastRun,
+	}
+	status.Active = state.Active
+
+	if state.CronJob != nil {
+		if next, err := state.CronJob.NextRun(); err == nil && !next.IsZero() {
+			status.NextRun = &next
+		}
+	}
+
+	// Runs from before the last restart only live in the database.
+	if status.LastRun == nil {
+		status.LastRun, err = s.latestRun(ctx, job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+func (s *Scheduler) JobStatuses(ctx context.Context) ([]*JobStatus, error) {
+	defs := s.Definitions()
+	statuses := make([]*JobStatus, 0, len(defs))
+
+	for _, def := range defs {
+		status, err := s.JobStatus(ctx, def.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (s *Scheduler) latestRun(ctx context.Context, job *job) (*m.JobRun, error) {
+	opts := options.FindOne().SetSort(bson.M{"_id": -1})
+
+	var run m.JobRun
+	err := s.deps.db.Collection("job_runs").FindOne(ctx, bson.M{"job": job.def.ID, "parent": nil}, opts).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
@@ -2,13 +2,17 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
+	"github.com/ArCaneSec/eagleeye/internal/events"
+	"github.com/ArCaneSec/eagleeye/internal/queue"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,67 +21,288 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (s *SubdomainEnumeration) Start(ctx context.Context, isSubTask bool) {
+// enumUnit is a root domain of a target waiting for its enumeration.
+type enumUnit struct {
+	target *m.Target
+	domain string
+}
+
+// key identifies the unit in checkpoints.
+func (u enumUnit) key() string {
+	return u.target.ID.Hex() + "/" + u.domain
+}
+
+func (s *SubdomainEnumeration) Start(ctx context.Context) (RunStats, error) {
 	s.wg.Add(1)
 	defer s.wg.Done()
 
+	var (
+		stats    RunStats
+		errs     error
+		mu       sync.Mutex
+		workers  sync.WaitGroup
+		queued   = make(chan enumUnit)
+		finished = map[string]bool{}
+	)
+
 	name := reflect.TypeOf(s).Elem().Name()
 	log.Printf("[*] %s started...\n", name)
 
+	ctx, due, err := s.narrowToDue(ctx, m.ScheduleEnumeration)
+	if err != nil || !due {
+		return stats, err
+	}
+
 	if err := s.fetchAssets(ctx); err != nil {
-		s.notify.ErrNotif(err)
+		return stats, err
 	}
 
-	var output string
-	var err error
-
-	for _, target := range s.targets {
-
-		for _, domain := range target.Scope {
-			select {
-			case <-ctx.Done():
-				s.notify.ErrNotif(
-					fmt.Errorf("[!] Context deadline exceeds in subdomain enumeration job"),
-				)
-				return
-
-			default:
-				output, err = s.runCommand(ctx, domain)
-				if err != nil {
-					s.notify.ErrNotif(err)
-					return
-				}
+	if err := s.loadFinders(ctx); err != nil {
+		return stats, err
+	}
 
-				checkNinsert := func() {
-					subs, err := s.checkResults(output, target.ID)
-					if err != nil {
-						if _, ok := err.(ErrNoResult); ok {
-							return
-						}
-						s.notify.ErrNotif(err)
-						return
-					}
+	cp, err := s.loadCheckpoint(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	units, found := s.plan(ctx, cp)
+	stats.New += found
 
-					s.insertDB(ctx, subs, target, domain)
+	if s.queue != nil {
+		return s.distribute(ctx, cp, units, stats)
+	}
+
+	// No domain is started past the soft deadline, the ones cut short by it
+	// still have what they found stored.
+	work, cancel := workContext(ctx)
+	defer cancel()
+
+	for i := 0; i < s.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			for unit := range queued {
+				found, err := s.enumerate(ctx, unit.target, unit.domain)
+				if work.Err() == nil && err == nil {
+					cp.markDone(ctx, unit.key())
 				}
 
-				if !isSubTask {
-					s.wg.Add(1)
-					go func() {
-						defer s.wg.Done()
-						checkNinsert()
-					}()
-				} else {
-					checkNinsert()
+				mu.Lock()
+				stats.New += found
+				errs = errors.Join(errs, err)
+				if work.Err() == nil {
+					finished[unit.key()] = true
+					if err != nil {
+						stats.Failed = append(stats.Failed, unit.domain)
+					}
 				}
+				mu.Unlock()
 			}
+		}()
+	}
+
+feed:
+	for _, unit := range units {
+		select {
+		case <-work.Done():
+			break feed
+		case queued <- unit:
+			stats.Input++
 		}
 	}
+
+	close(queued)
+	workers.Wait()
+
+	stats.Skipped = s.finish(work, cp, units, finished)
+
+	if work.Err() != nil {
+		return stats, errors.Join(errs, fmt.Errorf("[!] Context deadline exceeds in subdomain enumeration job: %w", errSoftDeadline))
+	}
+
 	log.Printf("[#] %s finished.\n", name)
+	return stats, errs
+}
+
+// plan stores the exact hosts of every target, which don't need enumeration,
+// and returns the root domains left to enumerate along with how many new
+// subdomains the hosts were. Domains the checkpoint has as done are left out.
+func (s *SubdomainEnumeration) plan(ctx context.Context, cp *checkpoint) ([]enumUnit, int) {
+	var units []enumUnit
+	found, resumed := 0, 0
+
+	for i := range s.targets {
+		target := &s.targets[i]
+
+		matcher, err := target.Matcher()
+		if err != nil {
+			s.notify.ErrNotif(fmt.Errorf("[!] Invalid scope for %s: %w", target.Name, err))
+			continue
+		}
+
+		if hosts := matcher.Hosts(); len(hosts) != 0 {
+			if subs, err := s.checkResults(strings.Join(hosts, "\n"), target); err == nil {
+				found += s.insertDB(ctx, subs, *target, target.Name)
+			}
+		}
+
+		for _, domain := range matcher.RootDomains() {
+			unit := enumUnit{target: target, domain: domain}
+			if cp.isDone(unit.key()) {
+				resumed++
+				continue
+			}
+			units = append(units, unit)
+		}
+	}
+
+	if resumed != 0 {
+		log.Printf("[~] Skipping %d domains already enumerated before the last run stopped.\n", resumed)
+	}
+
+	return units, found
+}
+
+// finish marks the targets whose domains were all gone through, failed ones
+// included, and returns the domains the run didn't get to. The checkpoint is
+// dropped if there's none, the next run retries the failed domains anyway.
+func (s *SubdomainEnumeration) finish(ctx context.Context, cp *checkpoint, units []enumUnit, finished map[string]bool) []string {
+	// The run's context may be over already, bookkeeping gets its own.
+	saveCtx, cancel := runsContext()
+	defer cancel()
+
+	var skipped []string
+	complete := map[*m.Target]bool{}
+
+	for _, unit := range units {
+		if _, ok := complete[unit.target]; !ok {
+			complete[unit.target] = true
+		}
+
+		if !finished[unit.key()] {
+			complete[unit.target] = false
+			skipped = append(skipped, unit.domain)
+		}
+	}
+
+	for i := range s.targets {
+		target := &s.targets[i]
+		if done, ok := complete[target]; done || !ok && ctx.Err() == nil {
+			s.markEnumerated(saveCtx, *target)
+		}
+	}
+
+	if len(skipped) != 0 {
+		log.Printf("[~] Didn't get to %d domains, the next run starts with them.\n", len(skipped))
+		return skipped
+	}
+
+	cp.clear(saveCtx)
+	return nil
 }
 
+// enumerate runs the enumeration of a single domain, retrying it as the
+// task's policy says, and returns how many new subdomains it found.
+func (s *SubdomainEnumeration) enumerate(ctx context.Context, target *m.Target, domain string) (int, error) {
+	var output string
+	_, err := s.retry.do(ctx, domain, func() error {
+		var err error
+		output, err = s.runCommand(ctx, domain)
+		return err
+	})
+	stopped := errors.Is(err, errSoftDeadline) && output != ""
+	if err != nil && !stopped {
+		// One domain failing shouldn't cost the others their run.
+		if ctx.Err() == nil {
+			log.Printf("[!] Skipping %s: %v\n", domain, err)
+		}
+		return 0, err
+	}
+
+	subs, checkErr := s.checkResults(output, target)
+	if checkErr != nil {
+		if _, ok := checkErr.(ErrNoResult); ok {
+			return 0, err
+		}
+		return 0, checkErr
+	}
+
+	return s.insertDB(ctx, subs, *target, domain), err
+}
+
+// distribute hands units to the workers and waits for them.
+func (s *SubdomainEnumeration) distribute(ctx context.Context, cp *checkpoint, units []enumUnit, stats RunStats) (RunStats, error) {
+	var errs error
+	finished := map[string]bool{}
+
+	payloads := make([]any, 0, len(units))
+	for _, unit := range units {
+		payloads = append(payloads, enumeratePayload{
+			Target:     unit.target.ID,
+			Domain:     unit.domain,
+			ScriptPath: s.scriptPath,
+			Tools:      s.toolNames,
+			Retry:      s.retry.def,
+		})
+	}
+	stats.Input = len(payloads)
+
+	if len(payloads) != 0 {
+		// Units that finished before waiting failed or timed out still count,
+		// and the run's context may be over already.
+		saveCtx, cancel := runsContext()
+		defer cancel()
+
+		results, err := s.dispatch(ctx, "enumerate-domain", payloads)
+		errs = err
+
+		for i, result := range results {
+			// Left to the next run, it never got to them.
+			if result.Status != queue.StatusDone && result.Status != queue.StatusFailed {
+				continue
+			}
+
+			stats.New += result.New
+			if result.Status == queue.StatusFailed {
+				errs = errors.Join(errs, fmt.Errorf("[!] Enumerating %s failed: %s", units[i].domain, result.Error))
+				stats.Failed = append(stats.Failed, units[i].domain)
+			} else {
+				cp.markDone(saveCtx, units[i].key())
+			}
+
+			finished[units[i].key()] = true
+		}
+	}
+
+	stats.Skipped = s.finish(ctx, cp, units, finished)
+	return stats, errs
+}
+
+func (s *SubdomainEnumeration) retriesItself() {}
+
+// markEnumerated records that every domain of target was enumerated, so it
+// moves behind the targets still waiting for their turn.
+func (t *SubdomainEnumeration) markEnumerated(ctx context.Context, target m.Target) {
+	update := bson.M{"$set": bson.M{"lastEnumerated": time.Now()}}
+
+	if _, err := t.db.Collection("targets").UpdateOne(ctx, bson.M{"_id": target.ID}, update); err != nil {
+		t.notify.ErrNotif(fmt.Errorf("[!] Error while updating last enumeration of %s: %w", target.Name, err))
+	}
+}
+
+// fetchAssets loads enabled targets, highest priority first and, within the
+// same priority, the ones that waited the longest since their last run.
 func (t *SubdomainEnumeration) fetchAssets(ctx context.Context) error {
-	cursor, _ := t.db.Collection("targets").Find(ctx, bson.D{{}})
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "lastEnumerated", Value: 1}})
+	// A target asked for by hand runs even if it's disabled.
+	filter := targetsFilter(ctx, bson.M{})
+	if paramsFrom(ctx).Target == "" {
+		filter["enabled"] = bson.M{"$ne": false}
+	}
+
+	cursor, _ := t.db.Collection("targets").Find(ctx, filter, opts)
 
 	if err := cursor.All(ctx, &t.targets); err != nil {
 		return fmt.Errorf("[!] Error while fetching targets: %w", err)
@@ -89,7 +314,14 @@ func (t *SubdomainEnumeration) runCommand(ctx context.Context, domain string) (s
 
 	log.Printf("[~] Current domain: %s\n", domain)
 
-	op, err := execute(ctx, &t.pgid, t.scriptPath, domain)
+	if len(t.finders) != 0 {
+		return t.runFinders(ctx, domain)
+	}
+
+	op, err := t.execute(ctx, &t.procGroup, t.scriptPath, domain)
+	if errors.Is(err, errSoftDeadline) {
+		return op, err
+	}
 
 	if err != nil {
 		return "", fmt.Errorf("[!] Error while enumerating subdomains: %w, %s", err, op)
@@ -97,37 +329,109 @@ func (t *SubdomainEnumeration) runCommand(ctx context.Context, domain string) (s
 	return op, err
 }
 
-func (t *SubdomainEnumeration) checkResults(output string, id primitive.ObjectID) ([]interface{}, error) {
+// loadFinders sets up the adapters of the task's tools, if it has any.
+func (t *SubdomainEnumeration) loadFinders(ctx context.Context) error {
+	t.finders = t.finders[:0]
+	for _, name := range t.toolNames {
+		finder, err := loadAdapter[tools.SubdomainFinder](ctx, t.Dependencies, &t.procGroup, name)
+		if err != nil {
+			return err
+		}
+		t.finders = append(t.finders, finder)
+	}
+
+	return nil
+}
+
+// runFinders merges what the finders found for domain. A finder failing only
+// fails the domain if none of them got through.
+func (t *SubdomainEnumeration) runFinders(ctx context.Context, domain string) (string, error) {
+	var (
+		subs   []string
+		errs   error
+		failed int
+		seen   = map[string]bool{}
+	)
+
+	for _, finder := range t.finders {
+		found, err := finder.FindSubdomains(ctx, domain)
+		stopped := errors.Is(err, errSoftDeadline)
+		if err != nil && !stopped {
+			log.Printf("[!] %s failed on %s: %v\n", finder.Name(), domain, err)
+			errs = errors.Join(errs, err)
+			failed++
+			continue
+		}
+
+		for _, sub := range found {
+			if !seen[sub] {
+				seen[sub] = true
+				subs = append(subs, sub)
+			}
+		}
+
+		// There's no time left for the other finders.
+		if stopped {
+			return strings.Join(subs, "\n"), err
+		}
+	}
+
+	if failed == len(t.finders) {
+		return "", fmt.Errorf("[!] Error while enumerating subdomains: %w", errs)
+	}
+
+	return strings.Join(subs, "\n"), nil
+}
+
+func (t *SubdomainEnumeration) checkResults(output string, target *m.Target) ([]interface{}, error) {
 	now := time.Now()
 
 	subs := strings.Split(strings.TrimSpace(output), "\n")
-	if len(subs) == 0 {
+	if len(subs) == 1 && subs[0] == "" {
 		return nil, ErrNoResult{}
 	}
 
 	subdomains := make([]interface{}, 0, len(subs))
+	excluded := 0
 
 	for _, sub := range subs {
-		subdomains = append(subdomains, m.Subdomain{Target: id, Subdomain: sub, Created: now})
+		// Out of scope subdomains are still stored so we can audit them, but the
+		// marker keeps every later job away from them.
+		exclusion := newExclusion(target, sub)
+		if exclusion != nil {
+			excluded++
+		}
+
+		subdomains = append(subdomains, m.Subdomain{
+			Target:    target.ID,
+			Subdomain: sub,
+			Excluded:  exclusion,
+			Created:   now,
+		})
+	}
+
+	if excluded != 0 {
+		log.Printf("[~] Marked %d subdomains of %s as out of scope.\n", excluded, target.Name)
 	}
 
 	return subdomains, nil
 }
 
-func (t *SubdomainEnumeration) insertDB(ctx context.Context, subs []interface{}, target m.Target, domain string) {
+// insertDB stores new subdomains and returns how many of them are in scope.
+func (t *SubdomainEnumeration) insertDB(ctx context.Context, subs []interface{}, target m.Target, domain string) int {
 	cursor := t.db.Collection("subdomains")
 	breakAfterFirstFail := options.InsertMany().SetOrdered(false)
 
 	val, err := t.db.Collection("subdomains").InsertMany(ctx, subs, breakAfterFirstFail)
 	if err != nil && !mongo.IsDuplicateKeyError(err) {
 		t.notify.ErrNotif(fmt.Errorf("[!] Error while inserting subdomains to database: %w", err))
-		return
+		return 0
 	}
 
 	if len(val.InsertedIDs) != 0 {
 		log.Printf("[+] Found %d new subdomains for %s.\n", len(val.InsertedIDs), target.Name)
 
-		filter := bson.D{{"_id", bson.D{{"$in", val.InsertedIDs}}}}
+		filter := bson.D{{"_id", bson.D{{"$in", val.InsertedIDs}}}, {"excluded", nil}}
 		values := options.Find().SetProjection(bson.D{{"subdomain", 1}})
 
 		newSubsRecords, _ := cursor.Find(context.TODO(), filter, values)
@@ -136,14 +440,18 @@ func (t *SubdomainEnumeration) insertDB(ctx context.Context, subs []interface{},
 		newSubsRecords.All(context.TODO(), &newSubsObjs)
 
 		var allSubs []string
+		ids := make([]primitive.ObjectID, 0, len(newSubsObjs))
 		for _, subObj := range newSubsObjs {
 			allSubs = append(allSubs, subObj.Subdomain)
+			ids = append(ids, subObj.ID)
 		}
 
-		t.notify.NewAssetNotif(target.Name, domain, allSubs)
+		if len(allSubs) != 0 {
+			t.notify.NewAssetNotif(target.Name, domain, allSubs)
+			t.publish(ctx, events.Event{Kind: events.SubdomainCreated, IDs: ids})
+		}
+		return len(allSubs)
 	}
-}
 
-func (s *SubdomainEnumeration) Kill() {
-	syscall.Kill(-s.pgid, syscall.SIGKILL)
+	return 0
 }
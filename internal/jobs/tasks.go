@@ -2,14 +2,20 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"log"
 	"reflect"
 	"strings"
 	"sync"
 
+	"github.com/ArCaneSec/eagleeye/internal/events"
 	"github.com/ArCaneSec/eagleeye/internal/notifs"
+	"github.com/ArCaneSec/eagleeye/internal/queue"
+	"github.com/ArCaneSec/eagleeye/internal/tools"
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
+	"github.com/ArCaneSec/eagleeye/pkg/platforms"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -19,117 +25,203 @@ func (err ErrNoResult) Error() string {
 	return ""
 }
 
+// RunStats is what a task reports about a single run, Input is how many
+// assets it worked on and New how many new ones it found. Skipped lists the
+// assets it didn't get to, if it stopped early, and Failed the ones it went
+// through without success.
+type RunStats struct {
+	Input   int
+	New     int
+	Skipped []string
+	Failed  []string
+}
+
 type Task interface {
-	Start(context.Context, bool)
+	Start(context.Context) (RunStats, error)
 	Kill()
 }
 
 type regularTask interface {
-	fetchAssets(context.Context) error
+	fetchAssets(context.Context) (int, error)
 	runCommand(context.Context) (string, error)
 	checkResults(string) ([]string, error)
-	insertDB(context.Context, []string) error
-	ErrNotif(error)
+	insertDB(context.Context, []string) (int, error)
 }
 
-func startRegularTask(ctx context.Context, t regularTask, wg *sync.WaitGroup, isSubTask bool) {
+func startRegularTask(ctx context.Context, t regularTask, wg *sync.WaitGroup) (RunStats, error) {
 	wg.Add(1)
 	defer wg.Done()
 
+	var stats RunStats
+	var err error
+
 	name := reflect.TypeOf(t).Elem().Name()
 	log.Printf("[*] %s started...\n", name)
 
-	if err := t.fetchAssets(ctx); err != nil {
-		t.ErrNotif(err)
-		return
+	if stats.Input, err = t.fetchAssets(ctx); err != nil {
+		return stats, err
 	}
 
-	output, err := t.runCommand(ctx)
-	if err != nil {
-		t.ErrNotif(err)
-		return
+	if stats.Input == 0 {
+		log.Printf("[#] %s finished, nothing to do.\n", name)
+		return stats, nil
 	}
 
-	checkNinsert := func() {
-		results, err := t.checkResults(output)
-		if err != nil {
-			if _, ok := err.(ErrNoResult); ok {
-				log.Printf("[#] %s finished successfully.\n", name)
-				return
-			}
-			t.ErrNotif(err)
-			return
-		}
+	// A tool stopped at the soft deadline still has its partial output
+	// stored, the run fails afterwards anyway.
+	output, stopped := t.runCommand(ctx)
+	if stopped != nil && (!errors.Is(stopped, errSoftDeadline) || output == "") {
+		return stats, stopped
+	}
 
-		if err := t.insertDB(ctx, results); err != nil {
-			t.ErrNotif(err)
-			return
+	results, err := t.checkResults(output)
+	if _, ok := err.(ErrNoResult); ok {
+		if stopped == nil {
+			log.Printf("[#] %s finished successfully.\n", name)
 		}
-		log.Printf("[#] %s finished successfully.\n", name)
+		return stats, stopped
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.New, err = t.insertDB(ctx, results); err != nil {
+		return stats, err
 	}
 
-	if !isSubTask {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			checkNinsert()
-		}()
-		return
+	if stopped != nil {
+		log.Printf("[~] %s was stopped at its soft deadline, stored what it found until then.\n", name)
+		return stats, stopped
 	}
-	checkNinsert()
+
+	log.Printf("[#] %s finished successfully.\n", name)
+	return stats, nil
 }
 
 type Dependencies struct {
 	db     *mongo.Database
 	notify notifs.Notify
 	wg     *sync.WaitGroup
-	pgid   int
+	events *events.Bus
+
+	// slots limits how many scheduled or manual runs go at once. Runs on
+	// events have eventSlots of their own, so a long scheduled run doesn't
+	// hold back the small ones reacting to new assets.
+	slots      chan struct{}
+	eventSlots chan struct{}
+	tools      *toolLimits
+
+	// versions holds the binaries whose version was logged already.
+	versions sync.Map
+
+	// queue is set when the work of tasks is spread across workers.
+	queue *queue.Queue
+}
+
+// RunParams narrow a run asked for by hand or by events down, a zero value
+// means the run works on everything like a scheduled one.
+type RunParams struct {
+	Target     string               `json:"target"`
+	Subdomains []primitive.ObjectID `json:"subdomains,omitempty"`
+	Services   []primitive.ObjectID `json:"services,omitempty"`
+
+	trigger string
+	job     primitive.ObjectID
+	step    string
+	run     primitive.ObjectID
+
+	// due are the only targets the run works on when it's narrowed down to
+	// the ones due by their schedules, nil if it isn't.
+	due []primitive.ObjectID
+	// postponed are the targets left out because their scan window is
+	// closed.
+	postponed []primitive.ObjectID
+}
+
+type paramsKey struct{}
+
+func withParams(ctx context.Context, params RunParams) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+func paramsFrom(ctx context.Context) RunParams {
+	params, _ := ctx.Value(paramsKey{}).(RunParams)
+	return params
 }
 
 type SubdomainEnumeration struct {
 	*Dependencies
+	procGroup
 	scriptPath string
 	targets    []m.Target
+	retry      retryPolicy
+	workers    int
+
+	// tools replace the script when set, their findings are merged.
+	toolNames []string
+	finders   []tools.SubdomainFinder
 }
 
 type DnsResolve struct {
 	*Dependencies
+	procGroup
 	scriptPath string
+	tool       string
 	subdomains []m.Subdomain
 	subsMap    map[string]*m.Subdomain
+
+	// partial is set when the tool was stopped at the soft deadline.
+	partial bool
 }
 
 type DnsResolveAll struct {
 	*DnsResolve
+	selector m.LabelSelector
 }
 
 type HttpDiscovery struct {
 	*Dependencies
+	procGroup
 	scriptPath string
+	tool       string
 	hosts      []m.HttpService
 	httpMap    map[string]*m.HttpService
+
+	// partial is set when the tool was stopped at the soft deadline.
+	partial bool
 }
 
 type HttpDiscoveryAll struct {
 	*HttpDiscovery
+	selector m.LabelSelector
 }
 
 type UpdateNuclei struct {
 	*Dependencies
+	procGroup
 	scriptPath string
 	configFile string `bson:"scriptsConfigFile"`
 }
 
 type RunNewTemplates struct {
 	*Dependencies
+	procGroup
 	scriptPath string
+	tool       string
+	selector   m.LabelSelector
+}
+
+type ScopeSync struct {
+	*Dependencies
+	client  *platforms.Client
+	targets []m.Target
 }
 
 type TestDiscord struct {
 	*Dependencies
 }
 
-func (t *TestDiscord) Start(ctx context.Context, b bool) {
+func (t *TestDiscord) Start(ctx context.Context) (RunStats, error) {
 	val := make([]string, 0, 9000000)
 
 	for i := 0; i <= 9000000; i++ {
@@ -137,4 +229,5 @@ func (t *TestDiscord) Start(ctx context.Context, b bool) {
 	}
 
 	t.notify.NucleiResultsNotif(strings.Join(val, ""))
+	return RunStats{}, nil
 }
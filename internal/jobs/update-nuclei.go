@@ -4,41 +4,41 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
-	"syscall"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (u *UpdateNuclei) Start(ctx context.Context, isSubTask bool) {
+func (u *UpdateNuclei) Start(ctx context.Context) (RunStats, error) {
 	u.wg.Add(1)
 	defer u.wg.Done()
 	log.Println("[*] UpdateNuclei started...")
 
 	if err := u.fetchConfig(ctx); err != nil {
-		u.notify.ErrNotif(err)
-		return
+		return RunStats{}, err
 	}
 
 	output, err := u.runCommand(ctx)
 	if err != nil {
-		u.notify.ErrNotif(err)
-		return
+		return RunStats{}, err
 	}
 
 	res, err := u.checkResults(output)
 	if err != nil {
 		if _, ok := err.(ErrNoResult); ok {
 			log.Println("[#] UpdateNuclei finished.")
-			return
+			return RunStats{}, nil
 		}
 
-		u.notify.ErrNotif(err)
-		return
+		return RunStats{}, err
 	}
 	log.Printf("[+] Found %s new templates.\n", res)
 	log.Println("[#] UpdateNuclei finished.")
+
+	templates, _ := strconv.Atoi(res)
+	return RunStats{New: templates}, nil
 }
 
 func (u *UpdateNuclei) fetchConfig(ctx context.Context) error {
@@ -60,7 +60,7 @@ func (u *UpdateNuclei) fetchConfig(ctx context.Context) error {
 }
 
 func (u *UpdateNuclei) runCommand(ctx context.Context) (string, error) {
-	results, err := execute(ctx, &u.pgid, u.scriptPath, u.configFile)
+	results, err := u.execute(ctx, &u.procGroup, u.scriptPath, u.configFile)
 
 	if err != nil {
 		return "", fmt.Errorf("[!] Error while executing update nuclei command: %w, %s", err, results)
@@ -77,7 +77,3 @@ func (u *UpdateNuclei) checkResults(output string) (string, error) {
 
 	return tmplCounts, nil
 }
-
-func (u *UpdateNuclei) Kill() {
-	syscall.Kill(-u.pgid, syscall.SIGKILL)
-}
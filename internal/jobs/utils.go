@@ -2,11 +2,17 @@ package jobs
 
 import (
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -95,4 +101,103 @@ func checkResults(results string) (string, error) {
 	}
 
 	return op, nil
-}
\ No newline at end of file
+}
+
+// selectedTargetIDs returns the ids of the targets matching selector and the
+// run's target if one was asked for, or nil if every target counts.
+func selectedTargetIDs(ctx context.Context, db *mongo.Database, selector m.LabelSelector) ([]primitive.ObjectID, error) {
+	filter := targetsFilter(ctx, selector.Filter())
+	if len(filter) == 0 {
+		return nil, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := db.Collection("targets").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while selecting targets by %q: %w", selector, err)
+	}
+
+	var targets []m.Target
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, fmt.Errorf("[!] Error while selecting targets by %q: %w", selector, err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+	}
+
+	return ids, nil
+}
+
+// subdomainsFilter narrows a subdomains query down to the targets matching selector.
+func subdomainsFilter(ctx context.Context, db *mongo.Database, selector m.LabelSelector, filter bson.M) (bson.M, error) {
+	if ids := paramsFrom(ctx).Subdomains; len(ids) != 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	ids, err := selectedTargetIDs(ctx, db, selector)
+	if err != nil || ids == nil {
+		return filter, err
+	}
+
+	filter["target"] = bson.M{"$in": ids}
+	return filter, nil
+}
+
+// servicesFilter narrows an http-services query down to the targets matching selector.
+func servicesFilter(ctx context.Context, db *mongo.Database, selector m.LabelSelector, filter bson.M) (bson.M, error) {
+	params := paramsFrom(ctx)
+	and := []bson.M{}
+
+	if len(params.Services) != 0 {
+		and = append(and, bson.M{"_id": bson.M{"$in": params.Services}})
+	}
+	if len(params.Subdomains) != 0 {
+		and = append(and, bson.M{"subdomain": bson.M{"$in": params.Subdomains}})
+	}
+
+	ids, err := selectedTargetIDs(ctx, db, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if ids != nil {
+		opts := options.Find().SetProjection(bson.M{"_id": 1})
+		cursor, err := db.Collection("subdomains").Find(ctx, bson.M{"target": bson.M{"$in": ids}}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("[!] Error while fetching subdomains of selected targets: %w", err)
+		}
+
+		var subs []m.Subdomain
+		if err := cursor.All(ctx, &subs); err != nil {
+			return nil, fmt.Errorf("[!] Error while fetching subdomains of selected targets: %w", err)
+		}
+
+		subIds := make([]primitive.ObjectID, 0, len(subs))
+		for _, sub := range subs {
+			subIds = append(subIds, sub.ID)
+		}
+
+		and = append(and, bson.M{"subdomain": bson.M{"$in": subIds}})
+	}
+
+	if len(and) != 0 {
+		filter["$and"] = and
+	}
+	return filter, nil
+}
+
+// targetsFilter narrows a targets query down to the run's target, if any,
+// and to the targets due by their schedules.
+func targetsFilter(ctx context.Context, filter bson.M) bson.M {
+	params := paramsFrom(ctx)
+	if params.Target != "" {
+		filter["name"] = params.Target
+	}
+	if params.due != nil {
+		filter["_id"] = bson.M{"$in": params.due}
+	}
+
+	return filter
+}
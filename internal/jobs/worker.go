@@ -0,0 +1,247 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ArCaneSec/eagleeye/internal/events"
+	"github.com/ArCaneSec/eagleeye/internal/queue"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// unitLease is how long a worker holds a unit without renewing its lease,
+	// a worker that died gives its units back after that long.
+	unitLease = 2 * time.Minute
+	unitsPoll = 10 * time.Second
+)
+
+type enumeratePayload struct {
+	Target     primitive.ObjectID `bson:"target"`
+	Domain     string             `bson:"domain"`
+	ScriptPath string             `bson:"scriptPath"`
+	Tools      []string           `bson:"tools,omitempty"`
+	Retry      *m.RetryPolicy     `bson:"retry,omitempty"`
+}
+
+type nucleiPayload struct {
+	Hosts     []string `bson:"hosts"`
+	Templates string   `bson:"templates"`
+	// TemplateList replaces Templates for templates owed to a target, they
+	// aren't in any file the worker can read.
+	TemplateList []string `bson:"templateList,omitempty"`
+	ScriptPath   string   `bson:"scriptPath"`
+	Tool         string   `bson:"tool,omitempty"`
+}
+
+// unitHandler runs a unit on a worker and returns how many new things it
+// found.
+type unitHandler func(ctx context.Context, d *Dependencies, unit *queue.Unit) (int, error)
+
+var unitHandlers = map[string]unitHandler{
+	"enumerate-domain": func(ctx context.Context, d *Dependencies, unit *queue.Unit) (int, error) {
+		var payload enumeratePayload
+		if err := unit.Decode(&payload); err != nil {
+			return 0, err
+		}
+
+		var target m.Target
+		if err := d.db.Collection("targets").FindOne(ctx, bson.M{"_id": payload.Target}).Decode(&target); err != nil {
+			return 0, fmt.Errorf("[!] Error while fetching target of %s: %w", payload.Domain, err)
+		}
+		if _, err := target.Matcher(); err != nil {
+			return 0, err
+		}
+
+		task := &SubdomainEnumeration{Dependencies: d, scriptPath: payload.ScriptPath, toolNames: payload.Tools, retry: newRetryPolicy(payload.Retry)}
+		if err := task.loadFinders(ctx); err != nil {
+			return 0, err
+		}
+		return task.enumerate(ctx, &target, payload.Domain)
+	},
+	"nuclei-chunk": func(ctx context.Context, d *Dependencies, unit *queue.Unit) (int, error) {
+		var payload nucleiPayload
+		if err := unit.Decode(&payload); err != nil {
+			return 0, err
+		}
+
+		if payload.TemplateList != nil {
+			listPath, err := writeList(payload.TemplateList)
+			if err != nil {
+				return 0, err
+			}
+			defer os.Remove(listPath)
+			payload.Templates = listPath
+		}
+
+		task := &RunNewTemplates{Dependencies: d, scriptPath: payload.ScriptPath, tool: payload.Tool}
+		return task.scanChunk(ctx, payload.Templates, payload.Hosts)
+	},
+}
+
+// unitEvents collects the events published while a unit runs, a worker's
+// bus has nobody listening so they go back to the scheduler with the unit.
+type unitEvents struct {
+	mu     sync.Mutex
+	events []queue.Event
+}
+
+type unitEventsKey struct{}
+
+// publish hands event to the bus, or to the unit running on ctx if there's
+// one.
+func (d *Dependencies) publish(ctx context.Context, event events.Event) {
+	recorder, ok := ctx.Value(unitEventsKey{}).(*unitEvents)
+	if !ok {
+		d.events.Publish(event)
+		return
+	}
+
+	if len(event.IDs) == 0 {
+		return
+	}
+
+	recorder.mu.Lock()
+	recorder.events = append(recorder.events, queue.Event{Kind: event.Kind, IDs: event.IDs})
+	recorder.mu.Unlock()
+}
+
+// dispatch hands payloads to the workers as units of kind and waits for all
+// of them to be done. The units are returned in the order of their payloads,
+// also when waiting fails, with a zero unit for the ones that didn't finish.
+func (d *Dependencies) dispatch(ctx context.Context, kind string, payloads []any) ([]queue.Unit, error) {
+	// Units stop at the soft deadline, leaving the step time to wrap up.
+	deadline, ok := softDeadline(ctx)
+	if !ok {
+		deadline, ok = ctx.Deadline()
+	}
+	if !ok {
+		deadline = time.Now().Add(24 * time.Hour)
+	}
+
+	ids, err := d.queue.Enqueue(ctx, paramsFrom(ctx).run, kind, deadline, payloads)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[~] Queued %d %s units.\n", len(ids), kind)
+
+	units, err := d.queue.Wait(ctx, ids, unitsPoll, func(unit queue.Unit) {
+		for _, event := range unit.Events {
+			d.events.Publish(events.Event{Kind: event.Kind, IDs: event.IDs})
+		}
+	})
+	if err != nil {
+		cancelCtx, cancel := runsContext()
+		defer cancel()
+
+		err = errors.Join(err, d.queue.Cancel(cancelCtx, ids))
+	}
+
+	byID := make(map[primitive.ObjectID]queue.Unit, len(units))
+	for _, unit := range units {
+		byID[unit.ID] = unit
+	}
+
+	ordered := make([]queue.Unit, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, byID[id])
+	}
+
+	return ordered, err
+}
+
+// RunWorker claims units from the queue and runs them, up to concurrency at
+// once, until ctx is done. Units still running then are given up and go to
+// another worker once their lease runs out.
+func RunWorker(ctx context.Context, db *mongo.Database, wg *sync.WaitGroup, name string, concurrency int) {
+	deps := newDependencies(db, wg)
+	deps.queue = queue.New(db)
+
+	kinds := make([]string, 0, len(unitHandlers))
+	for kind := range unitHandlers {
+		kinds = append(kinds, kind)
+	}
+
+	log.Printf("[*] Worker %s started, running up to %d units at once.\n", name, concurrency)
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			deps.work(ctx, name, kinds)
+		}()
+	}
+
+	workers.Wait()
+	log.Printf("[#] Worker %s stopped.\n", name)
+}
+
+func (d *Dependencies) work(ctx context.Context, name string, kinds []string) {
+	for ctx.Err() == nil {
+		unit, err := d.queue.Claim(ctx, name, kinds, unitLease)
+		if err != nil && ctx.Err() == nil {
+			log.Println(err)
+		}
+
+		if unit == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(unitsPoll):
+			}
+			continue
+		}
+
+		d.runUnit(ctx, unit)
+	}
+}
+
+func (d *Dependencies) runUnit(parent context.Context, unit *queue.Unit) {
+	ctx, cancel := context.WithDeadline(parent, unit.Deadline)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(unitLease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := d.queue.Heartbeat(ctx, unit, unitLease); errors.Is(err, queue.ErrLeaseLost) {
+				log.Printf("[!] Lost the lease of unit %s, giving it up.\n", unit.ID.Hex())
+				cancel()
+				return
+			}
+		}
+	}()
+
+	recorder := &unitEvents{}
+	ctx = context.WithValue(ctx, unitEventsKey{}, recorder)
+
+	log.Printf("[*] Running %s unit %s (attempt %d).\n", unit.Kind, unit.ID.Hex(), unit.Attempts)
+	found, err := unitHandlers[unit.Kind](ctx, d, unit)
+	if parent.Err() != nil {
+		// Shutting down, the unit goes to another worker.
+		return
+	}
+
+	saveCtx, saveCancel := runsContext()
+	defer saveCancel()
+
+	if err := d.queue.Complete(saveCtx, unit, found, recorder.events, err); err != nil {
+		log.Printf("[!] Couldn't record unit %s: %v\n", unit.ID.Hex(), err)
+	}
+}
@@ -11,6 +11,7 @@ type Notify interface {
 	NewDnsNotif(assets []string)
 	NewHttpNotif(hosts []string)
 	NucleiResultsNotif(string)
+	ScopeChangeNotif(target string, added []string, removed []string)
 }
 
 type Notif struct {
@@ -61,4 +62,20 @@ func (n Notif) NewHttpNotif(hosts []string) {
 
 func (n Notif) NucleiResultsNotif(results string) {
 	n.provider.SendMessage("Nuclei Results", "Nuclei results with newly templates.", "nuclei-results", results)
-}
\ No newline at end of file
+}
+
+func (n Notif) ScopeChangeNotif(target string, added []string, removed []string) {
+	changes := make([]string, 0, len(added)+len(removed))
+	for _, entry := range added {
+		changes = append(changes, fmt.Sprintf("+ %s", entry))
+	}
+	for _, entry := range removed {
+		changes = append(changes, fmt.Sprintf("- %s", entry))
+	}
+
+	n.provider.SendMessage("Scope Changes",
+		fmt.Sprintf("%d scope items added and %d removed for %s", len(added), len(removed), target),
+		"scope-changes",
+		strings.Join(changes, "\n"),
+	)
+}
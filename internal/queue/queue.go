@@ -0,0 +1,262 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusLeased    = "leased"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Unit is a piece of a task's work a worker can pick up on its own, e.g.
+// enumerating a single domain. Payload is up to the unit's Kind.
+type Unit struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind       string             `json:"kind"`
+	Run        primitive.ObjectID `json:"run"`
+	Payload    bson.Raw           `json:"-"`
+	Status     string             `json:"status"`
+	Worker     string             `json:"worker,omitempty" bson:"worker,omitempty"`
+	LeaseUntil *time.Time         `json:"leaseUntil,omitempty" bson:"leaseUntil,omitempty"`
+	Deadline   time.Time          `json:"deadline"`
+	Attempts   int                `json:"attempts"`
+	New        int                `json:"new"`
+	Events     []Event            `json:"-" bson:"events,omitempty"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	Created    time.Time          `json:"created"`
+	Updated    time.Time          `json:"updated"`
+}
+
+// Event is an event a unit's handler published on its worker, it's handed to
+// the scheduler's bus once the unit is done since a worker's bus has no
+// subscribers.
+type Event struct {
+	Kind string               `bson:"kind"`
+	IDs  []primitive.ObjectID `bson:"ids"`
+}
+
+// Decode unmarshals the unit's payload into v.
+func (u *Unit) Decode(v any) error {
+	return bson.Unmarshal(u.Payload, v)
+}
+
+// MaxAttempts is how many times a unit is handed out before it's given up
+// on, a unit that keeps taking its workers down would go around forever
+// otherwise.
+const MaxAttempts = 3
+
+// ErrLeaseLost is returned when a worker touches a unit it doesn't hold
+// anymore, its lease expired and another worker may have claimed it.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Queue hands units of work out to workers through the "task-units"
+// collection. A worker holds a unit for as long as it keeps renewing its
+// lease, a unit whose lease ran out goes to the next worker asking.
+type Queue struct {
+	units *mongo.Collection
+}
+
+func New(db *mongo.Database) *Queue {
+	return &Queue{units: db.Collection("task-units")}
+}
+
+func CreateIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("task-units").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "leaseUntil", Value: 1}}},
+		{Keys: bson.D{{Key: "run", Value: 1}}},
+	})
+
+	return err
+}
+
+// Enqueue adds units of kind to the queue, one for every payload, and
+// returns their ids.
+func (q *Queue) Enqueue(ctx context.Context, run primitive.ObjectID, kind string, deadline time.Time, payloads []any) ([]primitive.ObjectID, error) {
+	now := time.Now()
+	docs := make([]any, 0, len(payloads))
+
+	for _, payload := range payloads {
+		raw, err := bson.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("[!] Error while encoding %s unit: %w", kind, err)
+		}
+
+		docs = append(docs, Unit{
+			Kind:     kind,
+			Run:      run,
+			Payload:  raw,
+			Status:   StatusQueued,
+			Deadline: deadline,
+			Created:  now,
+			Updated:  now,
+		})
+	}
+
+	rs, err := q.units.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while enqueuing %s units: %w", kind, err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rs.InsertedIDs))
+	for _, id := range rs.InsertedIDs {
+		ids = append(ids, id.(primitive.ObjectID))
+	}
+
+	return ids, nil
+}
+
+// Claim leases the oldest available unit of one of kinds to worker, it
+// returns nil if there's none.
+func (q *Queue) Claim(ctx context.Context, worker string, kinds []string, lease time.Duration) (*Unit, error) {
+	if err := q.giveUp(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"kind":     bson.M{"$in": kinds},
+		"deadline": bson.M{"$gt": now},
+		"attempts": bson.M{"$lt": MaxAttempts},
+		"$or": []bson.M{
+			{"status": StatusQueued},
+			{"status": StatusLeased, "leaseUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"status": StatusLeased, "worker": worker, "leaseUntil": now.Add(lease), "updated": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"_id": 1}).SetReturnDocument(options.After)
+
+	var unit Unit
+	err := q.units.FindOneAndUpdate(ctx, filter, update, opts).Decode(&unit)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[!] Error while claiming a unit: %w", err)
+	}
+
+	return &unit, nil
+}
+
+// giveUp fails the units whose lease ran out on their last attempt.
+func (q *Queue) giveUp(ctx context.Context) error {
+	now := time.Now()
+	filter := bson.M{"status": StatusLeased, "leaseUntil": bson.M{"$lt": now}, "attempts": bson.M{"$gte": MaxAttempts}}
+	update := bson.M{"$set": bson.M{
+		"status":  StatusFailed,
+		"error":   fmt.Sprintf("gave up after %d attempts, its workers never finished it", MaxAttempts),
+		"updated": now,
+	}}
+
+	if _, err := q.units.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("[!] Error while giving up on units: %w", err)
+	}
+
+	return nil
+}
+
+// Heartbeat renews worker's lease on a unit.
+func (q *Queue) Heartbeat(ctx context.Context, unit *Unit, lease time.Duration) error {
+	now := time.Now()
+	return q.leased(ctx, unit, bson.M{"leaseUntil": now.Add(lease), "updated": now})
+}
+
+// Complete records a unit's outcome and the events it published, err being
+// nil if it succeeded.
+func (q *Queue) Complete(ctx context.Context, unit *Unit, found int, events []Event, err error) error {
+	fields := bson.M{"status": StatusDone, "new": found, "events": events, "updated": time.Now()}
+	if err != nil {
+		fields["status"], fields["error"] = StatusFailed, err.Error()
+	}
+
+	return q.leased(ctx, unit, fields)
+}
+
+func (q *Queue) leased(ctx context.Context, unit *Unit, fields bson.M) error {
+	filter := bson.M{"_id": unit.ID, "status": StatusLeased, "worker": unit.Worker}
+
+	rs, err := q.units.UpdateOne(ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("[!] Error while updating unit %s: %w", unit.ID.Hex(), err)
+	}
+	if rs.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Cancel drops the units of ids that are still waiting for, or held by, a
+// worker.
+func (q *Queue) Cancel(ctx context.Context, ids []primitive.ObjectID) error {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "status": bson.M{"$in": []string{StatusQueued, StatusLeased}}}
+	update := bson.M{"$set": bson.M{"status": StatusCancelled, "updated": time.Now()}}
+
+	if _, err := q.units.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("[!] Error while cancelling units: %w", err)
+	}
+
+	return nil
+}
+
+// Wait polls the units of ids until every one of them is done, failed or
+// cancelled and returns them. If ctx is done first, it returns the ones that
+// finished by then along with ctx's error. onDone, if not nil, is called with
+// every unit as soon as it's seen finished.
+func (q *Queue) Wait(ctx context.Context, ids []primitive.ObjectID, poll time.Duration, onDone func(Unit)) ([]Unit, error) {
+	units := make([]Unit, 0, len(ids))
+	seen := make([]primitive.ObjectID, 0, len(ids))
+	opts := options.Find().SetProjection(bson.M{"payload": 0})
+
+	for {
+		// Workers may all be gone, the units they left behind still fail.
+		if err := q.giveUp(ctx); err != nil {
+			return units, err
+		}
+
+		cursor, err := q.units.Find(ctx, bson.M{
+			"_id":    bson.M{"$in": ids, "$nin": seen},
+			"status": bson.M{"$in": []string{StatusDone, StatusFailed, StatusCancelled}},
+		}, opts)
+		if err != nil {
+			return units, fmt.Errorf("[!] Error while waiting for units: %w", err)
+		}
+
+		var finished []Unit
+		if err := cursor.All(ctx, &finished); err != nil {
+			return units, fmt.Errorf("[!] Error while waiting for units: %w", err)
+		}
+
+		for _, unit := range finished {
+			seen = append(seen, unit.ID)
+			units = append(units, unit)
+			if onDone != nil {
+				onDone(unit)
+			}
+		}
+
+		if len(units) == len(ids) {
+			return units, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return units, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ArCaneSec/eagleeye/internal/tools"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *Server) getSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	var config struct {
+		Schedules m.ScheduleClasses `bson:"schedules"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "schedules": 1})
+	err := s.db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, config.Schedules)
+}
+
+func (s *Server) updateSchedules(w http.ResponseWriter, r *http.Request) {
+	var schedules m.ScheduleClasses
+	if err := json.NewDecoder(r.Body).Decode(&schedules); err != nil {
+		http.Error(w, "[!] invalid data.", http.StatusBadRequest)
+		return
+	}
+
+	if errs := schedules.Validate(); len(errs) != 0 {
+		s.jsonEncode(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"schedules": schedules}}
+	if _, err := s.db.Collection("config").UpdateOne(ctx, bson.M{}, update, options.Update().SetUpsert(true)); err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusAccepted, "updated.")
+}
+
+// ToolStatus is a tool run through an adapter, with its configuration and the
+// version of its installed binary.
+type ToolStatus struct {
+	Name    string       `json:"name"`
+	Config  m.ToolConfig `json:"config"`
+	Version string       `json:"version,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+func (s *Server) listTools(w http.ResponseWriter, r *http.Request) {
+	// Asking every binary for its version takes longer than a query.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var config struct {
+		Tools map[string]m.ToolConfig `bson:"tools"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "tools": 1})
+	err := s.db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	statuses := make([]ToolStatus, 0, len(tools.Names()))
+	for _, name := range tools.Names() {
+		status := ToolStatus{Name: name, Config: config.Tools[name]}
+
+		if status.Version, err = s.scheduler.ToolVersion(ctx, name, status.Config); err != nil {
+			status.Error = err.Error()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	s.jsonEncode(w, http.StatusOK, statuses)
+}
+
+func (s *Server) updateTools(w http.ResponseWriter, r *http.Request) {
+	var configs map[string]m.ToolConfig
+	if err := json.NewDecoder(r.Body).Decode(&configs); err != nil {
+		http.Error(w, "[!] invalid data.", http.StatusBadRequest)
+		return
+	}
+
+	errs := map[string]map[string]string{}
+	for name, config := range configs {
+		if _, err := tools.New(name, config, nil); err != nil {
+			errs[name] = map[string]string{"error": fmt.Sprintf("unknown tool, must be one of %v.", tools.Names())}
+		} else if err := config.Validate(); err != "" {
+			errs[name] = map[string]string{"error": err}
+		}
+	}
+	if len(errs) != 0 {
+		s.jsonEncode(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"tools": configs}}
+	if _, err := s.db.Collection("config").UpdateOne(ctx, bson.M{}, update, options.Update().SetUpsert(true)); err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusAccepted, "updated.")
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ArCaneSec/eagleeye/internal/jobs"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	statuses, err := s.scheduler.JobStatuses(ctx)
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, statuses)
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	status, err := s.scheduler.JobStatus(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.jsonEncode(w, http.StatusNotFound, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, status)
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var def m.JobDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, "[!] invalid data.", http.StatusBadRequest)
+		return
+	}
+
+	if errs := def.Validate(); len(errs) != 0 {
+		s.jsonEncode(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := s.scheduler.CreateJob(ctx, def)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] Job already exists.")
+			return
+		}
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusCreated, created)
+}
+
+func (s *Server) updateJob(w http.ResponseWriter, r *http.Request) {
+	var def m.JobDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, "[!] invalid data.", http.StatusBadRequest)
+		return
+	}
+
+	if errs := def.Validate(); len(errs) != 0 {
+		s.jsonEncode(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updated, err := s.scheduler.UpdateJob(ctx, chi.URLParam(r, "id"), def)
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusAccepted, updated)
+}
+
+func (s *Server) deleteJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.scheduler.DeleteJob(ctx, chi.URLParam(r, "id")); err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, "deleted.")
+}
+
+// jobRuns returns the latest ?limit (50 by default) runs of a job.
+func (s *Server) jobRuns(w http.ResponseWriter, r *http.Request) {
+	limit := int64(50)
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		value, err := strconv.ParseInt(rawLimit, 10, 64)
+		if err != nil || value <= 0 {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] limit must be a positive number.")
+			return
+		}
+		limit = value
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	runs, err := s.scheduler.JobRuns(ctx, chi.URLParam(r, "id"), limit)
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, runs)
+}
+
+// runJob starts a job right away, its schedule stays as it is. The body is
+// optional and may narrow the run down to a single target.
+func (s *Server) runJob(w http.ResponseWriter, r *http.Request) {
+	var params jobs.RunParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "[!] invalid data.", http.StatusBadRequest)
+		return
+	}
+
+	if params.Target != "" {
+		ctx, cancel := queryContext()
+		defer cancel()
+
+		if _, ok := s.findTarget(ctx, w, params.Target); !ok {
+			return
+		}
+	}
+
+	if err := s.scheduler.RunNow(chi.URLParam(r, "id"), params); err != nil {
+		s.jsonEncode(w, http.StatusConflict, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusAccepted, "started.")
+}
+
+// cancelJob stops the current run of a job, its schedule stays as it is.
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.CancelJob(chi.URLParam(r, "id")); err != nil {
+		s.jsonEncode(w, http.StatusConflict, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusAccepted, "cancelling.")
+}
+
+func (s *Server) activeJob(w http.ResponseWriter, r *http.Request) {
+	err := s.scheduler.ActiveJob(chi.URLParam(r, "id"))
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+	s.jsonEncode(w, http.StatusOK, "activated")
+}
+
+func (s *Server) deactiveJob(w http.ResponseWriter, r *http.Request) {
+	err := s.scheduler.DeactiveJob(chi.URLParam(r, "id"))
+
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+	s.jsonEncode(w, http.StatusAccepted, "deactivated")
+}
+
+func (s *Server) deactiveAll(w http.ResponseWriter, r *http.Request) {
+	err := s.scheduler.Shutdown()
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, "all jobs deativated gracefully.")
+}
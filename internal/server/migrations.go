@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ArCaneSec/eagleeye/internal/history"
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type migration struct {
+	name string
+	run  func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations bring data stored by older versions in line with the current
+// one, each runs once and is then recorded under migrations in the config
+// collection.
+var migrations = []migration{
+	{name: "target-history", run: seedTargetHistory},
+	{name: "legacy-scope", run: migrateLegacyScopes},
+}
+
+func runMigrations(db *mongo.Database) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var config struct {
+		Migrations []string `bson:"migrations"`
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 0, "migrations": 1})
+	err := db.Collection("config").FindOne(ctx, bson.M{}, opts).Decode(&config)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Fatalf("[!] Error while fetching applied migrations, err: %v", err)
+	}
+
+	for _, migration := range migrations {
+		if slices.Contains(config.Migrations, migration.name) {
+			continue
+		}
+
+		log.Printf("[*] Running migration %s...\n", migration.name)
+		if err := migration.run(ctx, db); err != nil {
+			log.Fatalf("[!] Migration %s failed, err: %v", migration.name, err)
+		}
+
+		update := bson.M{"$addToSet": bson.M{"migrations": migration.name}}
+		if _, err := db.Collection("config").UpdateOne(ctx, bson.M{}, update, options.Update().SetUpsert(true)); err != nil {
+			log.Fatalf("[!] Error while recording migration %s, err: %v", migration.name, err)
+		}
+	}
+}
+
+// seedTargetHistory gives the targets stored before the history existed
+// their first version.
+func seedTargetHistory(ctx context.Context, db *mongo.Database) error {
+	cursor, err := db.Collection("targets").Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	var targets []m.Target
+	if err := cursor.All(ctx, &targets); err != nil {
+		return fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	for i := range targets {
+		if err := history.Seed(ctx, db, &targets[i]); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[+] Seeded the history of %d targets.\n", len(targets))
+	return nil
+}
+
+// migrateLegacyScopes rewrites the bare domains of stored scopes, which used
+// to cover their subdomains as well, see models.LegacyScope.
+func migrateLegacyScopes(ctx context.Context, db *mongo.Database) error {
+	cursor, err := db.Collection("targets").Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	var targets []m.Target
+	if err := cursor.All(ctx, &targets); err != nil {
+		return fmt.Errorf("[!] Error while fetching targets: %w", err)
+	}
+
+	for i := range targets {
+		target := &targets[i]
+
+		scope := m.LegacyScope(target.Scope)
+		if len(scope) == len(target.Scope) {
+			continue
+		}
+
+		if err := history.Seed(ctx, db, target); err != nil {
+			return err
+		}
+
+		update := bson.M{"$set": bson.M{"scope": scope}}
+		if _, err := db.Collection("targets").UpdateOne(ctx, bson.M{"_id": target.ID}, update); err != nil {
+			return fmt.Errorf("[!] Error while migrating scope of %s: %w", target.Name, err)
+		}
+
+		target.Scope = scope
+		if _, err := history.Record(ctx, db, target, "migration"); err != nil {
+			return err
+		}
+		log.Printf("[+] Added wildcards to the scope of %s.\n", target.Name)
+	}
+
+	return nil
+}
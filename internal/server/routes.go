@@ -1,15 +1,21 @@
 package server
 
 import (
+	"github.com/ArCaneSec/eagleeye/internal/history"
+	"github.com/ArCaneSec/eagleeye/internal/jobs"
 	m "github.com/ArCaneSec/eagleeye/pkg/models"
+	"github.com/ArCaneSec/eagleeye/pkg/platforms"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"net/http"
 	"time"
@@ -29,10 +35,13 @@ func (s *Server) createTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The id is the database's to pick, not the client's.
+	target.ID = primitive.NilObjectID
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = s.db.Collection("targets").InsertOne(ctx, target)
+	rs, err := s.db.Collection("targets").InsertOne(ctx, target)
 
 	if err != nil {
 		errMessage := map[string]string{}
@@ -40,13 +49,20 @@ func (s *Server) createTarget(w http.ResponseWriter, r *http.Request) {
 		if mongo.IsDuplicateKeyError(err) {
 			errMessage["error"] = "[!] Target already exits."
 		} else {
-			errMessage["error"] = fmt.Sprintf("[!] Unexpected error occures, err: %w", err)
+			errMessage["error"] = fmt.Sprintf("[!] Unexpected error occures, err: %v", err)
 		}
 
 		s.jsonEncode(w, http.StatusBadRequest, errMessage)
 		return
 	}
 
+	target.ID = rs.InsertedID.(primitive.ObjectID)
+	if _, err := history.Record(ctx, s.db, &target, "api"); err != nil {
+		errMessage := map[string]string{"error": fmt.Sprintf("[!] Target created, but its first version wasn't saved, err: %v", err)}
+		s.jsonEncode(w, http.StatusBadGateway, errMessage)
+		return
+	}
+
 	s.jsonEncode(w, http.StatusCreated, map[string]string{"message": "created."})
 }
 
@@ -58,56 +74,368 @@ func (s *Server) editTarget(w http.ResponseWriter, r *http.Request) {
 		s.jsonEncode(w, http.StatusBadRequest, errs)
 		return
 	}
-	update := bson.D{
-		{"$set", bson.D{
-			{"bounty", target.Bounty},
-			{"scope", target.Scope},
-			{"outOfScope", target.OutOfScope},
-		}},
+	fields := bson.D{
+		{"bounty", target.Bounty},
+		{"scope", target.Scope},
+		{"outOfScope", target.OutOfScope},
+		{"source", target.Source},
+	}
+	// Left out handles are kept, targets without one aren't synced.
+	if target.Handle != "" {
+		fields = append(fields, bson.E{Key: "handle", Value: target.Handle})
+	}
+	if target.Enabled != nil {
+		fields = append(fields, bson.E{Key: "enabled", Value: *target.Enabled})
+	}
+	if target.Priority != nil {
+		fields = append(fields, bson.E{Key: "priority", Value: *target.Priority})
+	}
+	// Left out labels are kept, an empty list drops them.
+	if target.Labels != nil {
+		fields = append(fields, bson.E{Key: "labels", Value: target.Labels})
+	}
+	// An empty schedule drops the target's own intervals.
+	if target.Schedule != nil {
+		fields = append(fields, bson.E{Key: "schedule", Value: target.Schedule})
 	}
+	// An empty window lifts the restrictions.
+	if target.ScanWindow != nil {
+		fields = append(fields, bson.E{Key: "scanWindow", Value: target.ScanWindow})
+	}
+	update := bson.D{{"$set", fields}}
+
+	ctx, cancel := queryContext()
+	defer cancel()
 
-	rs, err := s.db.Collection("targets").UpdateOne(queryContext(), bson.D{{"name", target.Name}}, update)
+	// Targets older than the history keep what they were before this edit.
+	var current m.Target
+	err := s.db.Collection("targets").FindOne(ctx, bson.M{"name": target.Name}).Decode(&current)
+	if err == nil {
+		err = history.Seed(ctx, s.db, &current)
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		s.jsonEncode(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+
+	rs, err := s.db.Collection("targets").UpdateOne(ctx, bson.D{{"name", target.Name}}, update)
 	if err != nil {
 		s.jsonEncode(w, http.StatusBadGateway, err)
 		return
 	}
 
+	if rs.MatchedCount != 0 {
+		var updated m.Target
+		err := s.db.Collection("targets").FindOne(ctx, bson.M{"name": target.Name}).Decode(&updated)
+		if err == nil {
+			_, err = history.Record(ctx, s.db, &updated, "api")
+		}
+		if err != nil {
+			errMessage := map[string]string{"error": fmt.Sprintf("[!] Target updated, but its new version wasn't saved, err: %v", err)}
+			s.jsonEncode(w, http.StatusBadGateway, errMessage)
+			return
+		}
+	}
+
 	message := map[string]string{"message": fmt.Sprintf("successfully updated %d record.", rs.MatchedCount)}
 	s.jsonEncode(w, http.StatusAccepted, message)
 }
 
-func (s *Server) activeJob(w http.ResponseWriter, r *http.Request) {
-	jobId := chi.URLParam(r, "id")
-	intJobId, _ := strconv.Atoi(jobId)
+func (s *Server) listTargets(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+
+	if labels := r.URL.Query().Get("labels"); labels != "" {
+		selector, err := m.ParseLabelSelector(labels)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadRequest, err)
+			return
+		}
+		filter = selector.Filter()
+	}
 
-	err := s.scheduler.ActiveJob(intJobId)
+	if source := r.URL.Query().Get("source"); source != "" {
+		filter["source"] = source
+	}
+
+	if bounty := r.URL.Query().Get("bounty"); bounty != "" {
+		value, err := strconv.ParseBool(bounty)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] bounty must be true or false.")
+			return
+		}
+		filter["bounty"] = value
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	cursor, err := s.db.Collection("targets").Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
 	if err != nil {
-		s.jsonEncode(w, http.StatusBadRequest, err)
+		s.jsonEncode(w, http.StatusBadGateway, err)
 		return
 	}
-	s.jsonEncode(w, http.StatusOK, "activated")
+
+	targets := []m.Target{}
+	if err := cursor.All(ctx, &targets); err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, targets)
+}
+
+func (s *Server) getTarget(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	target, ok := s.findTarget(ctx, w, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, target)
 }
 
-func (s *Server) deactiveJob(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("got called")
-	jobId := chi.URLParam(r, "id")
-	intJobId, _ := strconv.Atoi(jobId)
+// deleteTarget removes a target, and with ?cascade=true everything kept about
+// it as well: its subdomains, http services, history versions, pending
+// templates and the units it has done in checkpoints.
+func (s *Server) deleteTarget(w http.ResponseWriter, r *http.Request) {
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	target, ok := s.findTarget(ctx, w, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	deleted := map[string]int64{}
+
+	if cascade {
+		opts := options.Find().SetProjection(bson.M{"_id": 1})
+		cursor, err := s.db.Collection("subdomains").Find(ctx, bson.M{"target": target.ID}, opts)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+
+		var subs []m.Subdomain
+		if err := cursor.All(ctx, &subs); err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
 
-	err := s.scheduler.DeactiveJob(intJobId)
+		ids := make([]primitive.ObjectID, 0, len(subs))
+		for _, sub := range subs {
+			ids = append(ids, sub.ID)
+		}
+
+		rs, err := s.db.Collection("http-services").DeleteMany(ctx, bson.M{"subdomain": bson.M{"$in": ids}})
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+		deleted["http-services"] = rs.DeletedCount
+
+		rs, err = s.db.Collection("subdomains").DeleteMany(ctx, bson.M{"target": target.ID})
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+		deleted["subdomains"] = rs.DeletedCount
+
+		rs, err = s.db.Collection("pending-templates").DeleteMany(ctx, bson.M{"target": target.ID})
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+		deleted["pending-templates"] = rs.DeletedCount
 
+		versions, err := history.Delete(ctx, s.db, target)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+		deleted["target-versions"] = versions
+
+		checkpoints, err := jobs.ForgetTarget(ctx, s.db, target.ID)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadGateway, err)
+			return
+		}
+		deleted["checkpoints"] = checkpoints
+	}
+
+	rs, err := s.db.Collection("targets").DeleteOne(ctx, bson.M{"_id": target.ID})
 	if err != nil {
-		s.jsonEncode(w, http.StatusBadRequest, err)
+		s.jsonEncode(w, http.StatusBadGateway, err)
 		return
 	}
-	s.jsonEncode(w, http.StatusAccepted, "deactivated")
+	deleted["targets"] = rs.DeletedCount
+
+	s.jsonEncode(w, http.StatusOK, map[string]any{"message": "deleted.", "deleted": deleted})
 }
 
-func (s *Server) deactiveAll(w http.ResponseWriter, r *http.Request) {
-	err := s.scheduler.Shutdown()
+// importTargets creates targets from a platform's scope export sent as the
+// request body. With ?dryRun=true nothing is written and the targets that
+// would be created are returned instead. ?name and ?bounty override what the
+// export says, which is required for exports that lack them.
+func (s *Server) importTargets(w http.ResponseWriter, r *http.Request) {
+	source := chi.URLParam(r, "source")
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dryRun"))
+
+	programs, err := platforms.Parse(source, http.MaxBytesReader(w, r.Body, 10<<20))
 	if err != nil {
 		s.jsonEncode(w, http.StatusBadRequest, err)
 		return
 	}
 
-	s.jsonEncode(w, http.StatusOK, "all jobs deativated gracefully.")
+	if name := query.Get("name"); name != "" {
+		if len(programs) != 1 {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] name can only be set when importing a single program.")
+			return
+		}
+		programs[0].Name = name
+	}
+
+	if bounty := query.Get("bounty"); bounty != "" {
+		value, err := strconv.ParseBool(bounty)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] bounty must be true or false.")
+			return
+		}
+		for _, program := range programs {
+			program.Bounty = value
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results := make([]map[string]any, 0, len(programs))
+	for _, program := range programs {
+		target := program.Target(source)
+		result := map[string]any{"target": target}
+
+		if len(program.Skipped) != 0 {
+			result["skipped"] = program.Skipped
+		}
+
+		if errs := target.Validate(); len(errs) != 0 {
+			result["errors"] = errs
+		} else if dryRun {
+			result["status"] = "would be created"
+		} else if rs, err := s.db.Collection("targets").InsertOne(ctx, target); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				result["errors"] = "[!] Target already exits."
+			} else {
+				result["errors"] = err.Error()
+			}
+		} else {
+			result["status"] = "created"
+
+			target.ID = rs.InsertedID.(primitive.ObjectID)
+			if _, err := history.Record(ctx, s.db, &target, "import"); err != nil {
+				result["errors"] = fmt.Sprintf("[!] Its first version wasn't saved, err: %v", err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	s.jsonEncode(w, http.StatusOK, map[string]any{"dryRun": dryRun, "targets": results})
+}
+
+func (s *Server) listTargetVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	target, ok := s.findTarget(ctx, w, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	if err := history.Seed(ctx, s.db, target); err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	versions, err := history.List(ctx, s.db, target)
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, versions)
+}
+
+// compareTargetVersions returns the diff between ?from and ?to versions of a
+// target, ?to defaults to the latest version.
+func (s *Server) compareTargetVersions(w http.ResponseWriter, r *http.Request) {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		s.jsonEncode(w, http.StatusBadRequest, "[!] from must be a version number.")
+		return
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	target, ok := s.findTarget(ctx, w, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	if err := history.Seed(ctx, s.db, target); err != nil {
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return
+	}
+
+	var to *m.TargetVersion
+	if rawTo := r.URL.Query().Get("to"); rawTo != "" {
+		version, err := strconv.Atoi(rawTo)
+		if err != nil {
+			s.jsonEncode(w, http.StatusBadRequest, "[!] to must be a version number.")
+			return
+		}
+		to, err = history.Get(ctx, s.db, target, version)
+	} else {
+		to, err = history.Latest(ctx, s.db, target)
+	}
+	if err != nil || to == nil {
+		s.jsonEncode(w, http.StatusNotFound, "[!] Version not found.")
+		return
+	}
+
+	fromVersion, err := history.Get(ctx, s.db, target, from)
+	if err != nil {
+		s.jsonEncode(w, http.StatusNotFound, "[!] Version not found.")
+		return
+	}
+
+	s.jsonEncode(w, http.StatusOK, map[string]any{
+		"from": fromVersion,
+		"to":   to,
+		"diff": m.CompareVersions(fromVersion, to),
+	})
+}
+
+// findTarget fetches a target by name, writing the error response itself
+// when it can't.
+func (s *Server) findTarget(ctx context.Context, w http.ResponseWriter, name string) (*m.Target, bool) {
+	var target m.Target
+
+	err := s.db.Collection("targets").FindOne(ctx, bson.M{"name": name}).Decode(&target)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			s.jsonEncode(w, http.StatusNotFound, "[!] Target not found.")
+			return nil, false
+		}
+		s.jsonEncode(w, http.StatusBadGateway, err)
+		return nil, false
+	}
+
+	return &target, true
 }
@@ -3,15 +3,19 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/ArCaneSec/eagleeye/internal/history"
 	"github.com/ArCaneSec/eagleeye/internal/jobs"
+	"github.com/ArCaneSec/eagleeye/internal/queue"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -25,7 +29,21 @@ type Server struct {
 	scheduler *jobs.Scheduler
 }
 
-func InitializeEagleEye() {
+const (
+	ModeStandalone = "standalone"
+	ModeScheduler  = "scheduler"
+	ModeWorker     = "worker"
+)
+
+// InitializeEagleEye starts EagleEye in one of the modes above. A standalone
+// instance runs everything itself, a scheduler serves the API and queues the
+// work of its tasks for the workers to pick up.
+func InitializeEagleEye(mode string) {
+	if mode == ModeWorker {
+		runWorker()
+		return
+	}
+
 	r := chi.NewRouter()
 
 	var wg sync.WaitGroup
@@ -33,15 +51,34 @@ func InitializeEagleEye() {
 	httpServer := http.Server{Addr: "127.0.0.1:5000", Handler: r}
 
 	s := &Server{db: initDb()}
-	s.scheduler = jobs.ScheduleJobs(s.db, &wg)
+	runMigrations(s.db)
+	s.scheduler = jobs.ScheduleJobs(s.db, &wg, mode == ModeScheduler)
 
 	r.Use(middleware.Logger)
 
+	r.Get("/target/", s.listTargets)
 	r.Post("/target/", s.createTarget)
 	r.Put("/target/", s.editTarget)
-	r.Post("/job/{id:[0-9]{1,3}}", s.activeJob)
-	r.Delete("/job/{id:[0-9]{1,3}}", s.deactiveJob)
+	r.Post("/target/import/{source}", s.importTargets)
+	r.Get("/target/{name}", s.getTarget)
+	r.Get("/target/{name}/versions", s.listTargetVersions)
+	r.Get("/target/{name}/versions/compare", s.compareTargetVersions)
+	r.Delete("/target/{name}", s.deleteTarget)
+	r.Get("/job/", s.listJobs)
+	r.Get("/job/{id:[0-9a-f]{24}}", s.getJob)
+	r.Post("/job/{id:[0-9a-f]{24}}", s.activeJob)
+	r.Delete("/job/{id:[0-9a-f]{24}}", s.deactiveJob)
 	r.Delete("/job/", s.deactiveAll)
+	r.Get("/job/{id:[0-9a-f]{24}}/runs", s.jobRuns)
+	r.Post("/job/{id:[0-9a-f]{24}}/run", s.runJob)
+	r.Post("/job/{id:[0-9a-f]{24}}/cancel", s.cancelJob)
+	r.Post("/job/definition/", s.createJob)
+	r.Put("/job/definition/{id:[0-9a-f]{24}}", s.updateJob)
+	r.Delete("/job/definition/{id:[0-9a-f]{24}}", s.deleteJob)
+	r.Get("/config/schedules", s.getSchedules)
+	r.Put("/config/schedules", s.updateSchedules)
+	r.Get("/config/tools", s.listTools)
+	r.Put("/config/tools", s.updateTools)
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, os.Interrupt)
@@ -78,6 +115,28 @@ func InitializeEagleEye() {
 	log.Println("[#] Eagle's going to sleep, cya!")
 }
 
+// runWorker claims and runs queued units until it's told to stop.
+func runWorker() {
+	var wg sync.WaitGroup
+	db := initDb()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	concurrency, err := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY"))
+	if err != nil || concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs.RunWorker(ctx, db, &wg, name, concurrency)
+	wg.Wait()
+
+	log.Println("[#] Eagle's going to sleep, cya!")
+}
+
 func initDb() *mongo.Database {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -85,12 +144,12 @@ func initDb() *mongo.Database {
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017/"))
 
 	if err != nil {
-		log.Fatalf("[!] Could not connect to database. err: %w", err)
+		log.Fatalf("[!] Could not connect to database. err: %v", err)
 	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatalf("[!] Database ping wasnt successfull. err: %w", err)
+		log.Fatalf("[!] Database ping wasnt successfull. err: %v", err)
 	}
 
 	indexModel := mongo.IndexModel{
@@ -102,7 +161,7 @@ func initDb() *mongo.Database {
 	_, err = db.Collection("targets").Indexes().CreateOne(ctx, indexModel)
 
 	if err != nil {
-		log.Fatalf("[!] An error occured when tried to create index for targets collection, err: %w", err)
+		log.Fatalf("[!] An error occured when tried to create index for targets collection, err: %v", err)
 	}
 
 	sdIndexModel := mongo.IndexModel{
@@ -111,7 +170,7 @@ func initDb() *mongo.Database {
 	}
 	_, err = db.Collection("subdomains").Indexes().CreateOne(ctx, sdIndexModel)
 	if err != nil {
-		log.Fatalf("[!] Error while tried to create index for subdomains collection, err: %w", err)
+		log.Fatalf("[!] Error while tried to create index for subdomains collection, err: %v", err)
 	}
 
 	hsIndexModel := mongo.IndexModel{
@@ -121,11 +180,42 @@ func initDb() *mongo.Database {
 	_, err = db.Collection("http-services").Indexes().CreateOne(ctx, hsIndexModel)
 	if err != nil {
 		log.Fatalf(
-			"[!] Error while tried to create index for http-services collection, err: %w",
+			"[!] Error while tried to create index for http-services collection, err: %v",
 			err,
 		)
 	}
 
+	_, err = db.Collection("jobs").Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatalf("[!] Error while tried to create index for jobs collection, err: %v", err)
+	}
+
+	runsIndexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "job", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "parent", Value: 1}}},
+	}
+	_, err = db.Collection("job_runs").Indexes().CreateMany(ctx, runsIndexModels)
+	if err != nil {
+		log.Fatalf("[!] Error while tried to create indexes for job_runs collection, err: %v", err)
+	}
+
+	checkpointsIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "job", Value: 1}, {Key: "step", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = db.Collection("checkpoints").Indexes().CreateOne(ctx, checkpointsIndexModel)
+	if err != nil {
+		log.Fatalf("[!] Error while tried to create index for checkpoints collection, err: %v", err)
+	}
+
+	if err = history.CreateIndexes(ctx, db); err != nil {
+		log.Fatalf("[!] Error while tried to create index for target-versions collection, err: %v", err)
+	}
+
+	if err = queue.CreateIndexes(ctx, db); err != nil {
+		log.Fatalf("[!] Error while tried to create indexes for task-units collection, err: %v", err)
+	}
+
 	return db
 
 }
@@ -143,7 +233,7 @@ func (s *Server) jsonEncode(w http.ResponseWriter, sCode int, data any) {
 	err := json.NewEncoder(w).Encode(data)
 
 	if err != nil {
-		log.Fatalf("[!] error while serializing data, err: %w", err)
+		log.Fatalf("[!] error while serializing data, err: %v", err)
 		return
 	}
 }
@@ -5,7 +5,6 @@ import (
 	"time"
 )
 
-func queryContext() context.Context {
-	ctx, _ := context.WithTimeout(context.Background(), 4 * time.Second)
-	return ctx
-}
\ No newline at end of file
+func queryContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 4*time.Second)
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+type dnsx struct {
+	base
+}
+
+func newDnsx(config m.ToolConfig, run Runner) Tool {
+	return &dnsx{base{name: "dnsx", config: config, run: run, versionArgs: []string{"-version"}, rateFlag: "-rl"}}
+}
+
+func (d *dnsx) Resolve(ctx context.Context, hosts []string) ([]string, error) {
+	output, err := withList(hosts, func(path string) (string, error) {
+		return d.exec(ctx, "-l", path, "-silent", "-duc")
+	})
+
+	return lines(output), err
+}
+
+type httpx struct {
+	base
+}
+
+func newHttpx(config m.ToolConfig, run Runner) Tool {
+	return &httpx{base{name: "httpx", config: config, run: run, versionArgs: []string{"-version"}, rateFlag: "-rl"}}
+}
+
+func (h *httpx) Probe(ctx context.Context, hosts []string) ([]string, error) {
+	output, err := withList(hosts, func(path string) (string, error) {
+		return h.exec(ctx, "-l", path, "-silent", "-duc", "-nc")
+	})
+
+	return lines(output), err
+}
+
+type nuclei struct {
+	base
+}
+
+func newNuclei(config m.ToolConfig, run Runner) Tool {
+	return &nuclei{base{name: "nuclei", config: config, run: run, versionArgs: []string{"-version"}, rateFlag: "-rl"}}
+}
+
+func (n *nuclei) Scan(ctx context.Context, hosts []string, templates string) ([]string, error) {
+	output, err := withList(hosts, func(path string) (string, error) {
+		return n.exec(ctx, "-l", path, "-t", templates, "-silent", "-nc", "-duc")
+	})
+
+	return lines(output), err
+}
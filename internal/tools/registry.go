@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+type factory func(config m.ToolConfig, run Runner) Tool
+
+var adapters = map[string]factory{
+	"subfinder":   newSubfinder,
+	"amass":       newAmass,
+	"assetfinder": newAssetfinder,
+	"dnsx":        newDnsx,
+	"httpx":       newHttpx,
+	"nuclei":      newNuclei,
+}
+
+// New returns the adapter of the tool called name, set up with config and
+// running its binary through run.
+func New(name string, config m.ToolConfig, run Runner) (Tool, error) {
+	factory, ok := adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+
+	return factory(config, run), nil
+}
+
+// Names lists the tools there's an adapter for.
+func Names() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
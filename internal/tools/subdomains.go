@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// finder adapts the subdomain finders, they only differ in their arguments.
+type finder struct {
+	base
+	domainArgs func(domain string) []string
+}
+
+func (f *finder) FindSubdomains(ctx context.Context, domain string) ([]string, error) {
+	output, err := f.exec(ctx, f.domainArgs(domain)...)
+	return subdomainsOf(output, domain), err
+}
+
+// subdomainsOf picks the names under domain out of a finder's output, some
+// of them print more than names on a line.
+func subdomainsOf(output, domain string) []string {
+	domain = strings.ToLower(domain)
+	seen := map[string]bool{}
+
+	var subs []string
+	for _, line := range lines(output) {
+		name := strings.TrimSuffix(strings.ToLower(strings.Fields(line)[0]), ".")
+		if name != domain && !strings.HasSuffix(name, "."+domain) || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		subs = append(subs, name)
+	}
+
+	return subs
+}
+
+func newSubfinder(config m.ToolConfig, run Runner) Tool {
+	return &finder{
+		base: base{name: "subfinder", config: config, run: run, versionArgs: []string{"-version"}, rateFlag: "-rl"},
+		domainArgs: func(domain string) []string {
+			return []string{"-d", domain, "-silent", "-duc"}
+		},
+	}
+}
+
+func newAmass(config m.ToolConfig, run Runner) Tool {
+	return &finder{
+		base: base{name: "amass", config: config, run: run, versionArgs: []string{"-version"}, rateFlag: "-dns-qps"},
+		domainArgs: func(domain string) []string {
+			return []string{"enum", "-passive", "-nocolor", "-d", domain}
+		},
+	}
+}
+
+// assetfinder has neither a version flag nor a rate limit.
+func newAssetfinder(config m.ToolConfig, run Runner) Tool {
+	return &finder{
+		base: base{name: "assetfinder", config: config, run: run},
+		domainArgs: func(domain string) []string {
+			return []string{"--subs-only", domain}
+		},
+	}
+}
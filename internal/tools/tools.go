@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// Runner runs command and returns what it printed. It's how adapters go
+// through the process groups and limits of the task using them, output may
+// hold partial results even along with an error.
+type Runner func(ctx context.Context, command string, args ...string) (string, error)
+
+type stderrKey struct{}
+
+// WithStderr asks the Runner for what the command printed on stderr along
+// with its stdout, even when it succeeds. Most tools print their version
+// there.
+func WithStderr(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stderrKey{}, true)
+}
+
+// WantsStderr reports whether ctx was made by WithStderr.
+func WantsStderr(ctx context.Context) bool {
+	wants, _ := ctx.Value(stderrKey{}).(bool)
+	return wants
+}
+
+// Tool is an external program behind an adapter that knows its flags and
+// output format.
+type Tool interface {
+	Name() string
+	// Version detects the version of the installed binary, "unknown" for
+	// tools that can't tell it.
+	Version(ctx context.Context) (string, error)
+}
+
+// SubdomainFinder finds subdomains of a root domain.
+type SubdomainFinder interface {
+	Tool
+	FindSubdomains(ctx context.Context, domain string) ([]string, error)
+}
+
+// Resolver returns the hosts that have dns records.
+type Resolver interface {
+	Tool
+	Resolve(ctx context.Context, hosts []string) ([]string, error)
+}
+
+// Prober returns the urls of the host:port pairs serving http.
+type Prober interface {
+	Tool
+	Probe(ctx context.Context, hosts []string) ([]string, error)
+}
+
+// Scanner runs templates against hosts and returns its findings.
+type Scanner interface {
+	Tool
+	Scan(ctx context.Context, hosts []string, templates string) ([]string, error)
+}
+
+var versionPattern = regexp.MustCompile(`v?\d+\.\d+\.\d+`)
+
+// base holds what every adapter shares: its configuration, how to reach the
+// binary and how to ask it for its version.
+type base struct {
+	name        string
+	config      m.ToolConfig
+	run         Runner
+	versionArgs []string
+	rateFlag    string
+}
+
+func (b *base) Name() string {
+	return b.name
+}
+
+func (b *base) path() string {
+	if b.config.Path != "" {
+		return b.config.Path
+	}
+
+	return b.name
+}
+
+// args returns the adapter's own arguments followed by the rate limit and
+// the configured flags.
+func (b *base) args(own ...string) []string {
+	args := append([]string(nil), own...)
+	if b.config.RateLimit > 0 && b.rateFlag != "" {
+		args = append(args, b.rateFlag, strconv.Itoa(b.config.RateLimit))
+	}
+
+	return append(args, b.config.Flags...)
+}
+
+func (b *base) exec(ctx context.Context, own ...string) (string, error) {
+	return b.run(ctx, b.path(), b.args(own...)...)
+}
+
+func (b *base) Version(ctx context.Context) (string, error) {
+	if b.versionArgs == nil {
+		return "unknown", nil
+	}
+
+	// Some tools exit non zero after printing it.
+	output, err := b.run(WithStderr(ctx), b.path(), b.versionArgs...)
+	if version := versionPattern.FindString(output); version != "" {
+		return version, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("[!] Error while detecting version of %s: %w", b.name, err)
+	}
+	return "", fmt.Errorf("[!] Couldn't find the version of %s in its output", b.name)
+}
+
+// withList writes hosts to a temporary file, one per line, for the tools
+// reading their input from a file, and calls fn with its path.
+func withList(hosts []string, fn func(path string) (string, error)) (string, error) {
+	tempFile, err := os.CreateTemp("/tmp/", "hosts")
+	if err != nil {
+		return "", fmt.Errorf("[!] Error creating temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.WriteString(strings.Join(hosts, "\n") + "\n")
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("[!] Error writing temp file: %w", err)
+	}
+
+	return fn(tempFile.Name())
+}
+
+// lines splits output into its non empty, trimmed lines.
+func lines(output string) []string {
+	var result []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
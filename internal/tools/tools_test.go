@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// recorder is a Runner keeping the last command it was asked to run.
+type recorder struct {
+	command string
+	args    []string
+	stderr  bool
+	list    string
+	output  string
+	err     error
+}
+
+func (r *recorder) run(ctx context.Context, command string, args ...string) (string, error) {
+	r.command, r.args, r.stderr = command, args, WantsStderr(ctx)
+
+	// The host list is gone once the adapter returns.
+	for i, arg := range args {
+		if arg == "-l" && i+1 < len(args) {
+			list, _ := os.ReadFile(args[i+1])
+			r.list = string(list)
+			args[i+1] = "<list>"
+		}
+	}
+
+	return r.output, r.err
+}
+
+func TestSubdomainsOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		domain string
+		want   []string
+	}{
+		{name: "plain", output: "a.example.com\nb.example.com\n", domain: "example.com", want: []string{"a.example.com", "b.example.com"}},
+		{name: "blank lines", output: "\n  a.example.com  \n\n", domain: "example.com", want: []string{"a.example.com"}},
+		{name: "duplicates", output: "a.example.com\nA.Example.com.\na.example.com", domain: "example.com", want: []string{"a.example.com"}},
+		{name: "root", output: "example.com\n", domain: "EXAMPLE.com", want: []string{"example.com"}},
+		{name: "extra fields", output: "a.example.com (FQDN) --> a_record --> 1.2.3.4\n", domain: "example.com", want: []string{"a.example.com"}},
+		{name: "other domains", output: "badexample.com\nexample.com.evil\nexample.org\n", domain: "example.com", want: nil},
+		{name: "empty", output: "", domain: "example.com", want: nil},
+	}
+
+	for _, test := range tests {
+		if got := subdomainsOf(test.output, test.domain); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: subdomainsOf = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBaseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		base base
+		own  []string
+		want []string
+	}{
+		{name: "defaults", base: base{rateFlag: "-rl"}, own: []string{"-silent"}, want: []string{"-silent"}},
+		{
+			name: "rate limit after own",
+			base: base{rateFlag: "-rl", config: m.ToolConfig{RateLimit: 10}},
+			own:  []string{"-d", "example.com"},
+			want: []string{"-d", "example.com", "-rl", "10"},
+		},
+		{
+			name: "flags last",
+			base: base{rateFlag: "-dns-qps", config: m.ToolConfig{RateLimit: 5, Flags: []string{"-timeout", "3"}}},
+			own:  []string{"enum"},
+			want: []string{"enum", "-dns-qps", "5", "-timeout", "3"},
+		},
+		{
+			name: "no rate flag",
+			base: base{config: m.ToolConfig{RateLimit: 5, Flags: []string{"-v"}}},
+			own:  []string{"--subs-only"},
+			want: []string{"--subs-only", "-v"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.base.args(test.own...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: args = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	// Building the arguments mustn't write into the adapter's own.
+	own := make([]string, 1, 4)
+	own[0] = "-silent"
+	b := base{rateFlag: "-rl", config: m.ToolConfig{RateLimit: 1}}
+	b.args(own...)
+	if got := own[:cap(own)][1]; got != "" {
+		t.Errorf("args wrote %q past the adapter's arguments", got)
+	}
+}
+
+func TestFinderArgs(t *testing.T) {
+	config := m.ToolConfig{Path: "/opt/bin/tool", RateLimit: 20, Flags: []string{"-x"}}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "subfinder", want: []string{"-d", "example.com", "-silent", "-duc", "-rl", "20", "-x"}},
+		{name: "amass", want: []string{"enum", "-passive", "-nocolor", "-d", "example.com", "-dns-qps", "20", "-x"}},
+		{name: "assetfinder", want: []string{"--subs-only", "example.com", "-x"}},
+	}
+
+	for _, test := range tests {
+		rec := &recorder{output: "a.example.com\n"}
+		tool, err := New(test.name, config, rec.run)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subs, err := tool.(SubdomainFinder).FindSubdomains(context.Background(), "example.com")
+		if err != nil {
+			t.Errorf("%s: FindSubdomains failed: %v", test.name, err)
+		}
+		if rec.command != config.Path {
+			t.Errorf("%s: ran %q, want %q", test.name, rec.command, config.Path)
+		}
+		if !reflect.DeepEqual(rec.args, test.want) {
+			t.Errorf("%s: args = %q, want %q", test.name, rec.args, test.want)
+		}
+		if !reflect.DeepEqual(subs, []string{"a.example.com"}) {
+			t.Errorf("%s: found %q", test.name, subs)
+		}
+	}
+}
+
+func TestListArgs(t *testing.T) {
+	hosts := []string{"a.example.com", "b.example.com:8443"}
+	config := m.ToolConfig{RateLimit: 50}
+
+	tests := []struct {
+		name string
+		call func(tool Tool) ([]string, error)
+		want []string
+	}{
+		{
+			name: "dnsx",
+			call: func(tool Tool) ([]string, error) { return tool.(Resolver).Resolve(context.Background(), hosts) },
+			want: []string{"-l", "<list>", "-silent", "-duc", "-rl", "50"},
+		},
+		{
+			name: "httpx",
+			call: func(tool Tool) ([]string, error) { return tool.(Prober).Probe(context.Background(), hosts) },
+			want: []string{"-l", "<list>", "-silent", "-duc", "-nc", "-rl", "50"},
+		},
+		{
+			name: "nuclei",
+			call: func(tool Tool) ([]string, error) {
+				return tool.(Scanner).Scan(context.Background(), hosts, "/templates/new")
+			},
+			want: []string{"-l", "<list>", "-t", "/templates/new", "-silent", "-nc", "-duc", "-rl", "50"},
+		},
+	}
+
+	for _, test := range tests {
+		rec := &recorder{output: "first\n\n second \n", err: errors.New("exit status 1")}
+		tool, err := New(test.name, config, rec.run)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := test.call(tool)
+		if err == nil {
+			t.Errorf("%s: the runner's error was dropped", test.name)
+		}
+		if rec.command != test.name {
+			t.Errorf("%s: ran %q, want it looked up by name", test.name, rec.command)
+		}
+		if !reflect.DeepEqual(rec.args, test.want) {
+			t.Errorf("%s: args = %q, want %q", test.name, rec.args, test.want)
+		}
+		if want := strings.Join(hosts, "\n") + "\n"; rec.list != want {
+			t.Errorf("%s: host list = %q, want %q", test.name, rec.list, want)
+		}
+		// Partial output is kept along with the error.
+		if !reflect.DeepEqual(got, []string{"first", "second"}) {
+			t.Errorf("%s: returned %q", test.name, got)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+		fails  bool
+	}{
+		{name: "nuclei", output: "[INF] Nuclei Engine Version: v3.2.9\n", want: "v3.2.9"},
+		{name: "amass", output: "v4.2.0\n", err: errors.New("exit status 1"), want: "v4.2.0"},
+		{name: "dnsx", output: "no version here", fails: true},
+		{name: "httpx", err: errors.New("executable file not found"), fails: true},
+		{name: "assetfinder", want: "unknown"},
+	}
+
+	for _, test := range tests {
+		rec := &recorder{output: test.output, err: test.err}
+		tool, err := New(test.name, m.ToolConfig{RateLimit: 10, Flags: []string{"-x"}}, rec.run)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tool.Version(context.Background())
+		if (err != nil) != test.fails {
+			t.Errorf("%s: Version error = %v, want failure %t", test.name, err, test.fails)
+		}
+		if got != test.want {
+			t.Errorf("%s: Version = %q, want %q", test.name, got, test.want)
+		}
+
+		if test.name == "assetfinder" {
+			if rec.command != "" {
+				t.Errorf("assetfinder ran %q for its version", rec.command)
+			}
+			continue
+		}
+		// Only the version flag, the configured ones may need a target.
+		if !reflect.DeepEqual(rec.args, []string{"-version"}) {
+			t.Errorf("%s: version args = %q", test.name, rec.args)
+		}
+		if !rec.stderr {
+			t.Errorf("%s: version asked without stderr", test.name)
+		}
+	}
+}
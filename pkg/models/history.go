@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScopeDiff lists what changed between two versions of a target.
+type ScopeDiff struct {
+	Bounty            *bool    `json:"bounty,omitempty" bson:"bounty,omitempty"`
+	AddedScope        []string `json:"addedScope" bson:"addedScope"`
+	RemovedScope      []string `json:"removedScope" bson:"removedScope"`
+	AddedOutOfScope   []string `json:"addedOutOfScope" bson:"addedOutOfScope"`
+	RemovedOutOfScope []string `json:"removedOutOfScope" bson:"removedOutOfScope"`
+}
+
+func (d ScopeDiff) IsEmpty() bool {
+	return d.Bounty == nil &&
+		len(d.AddedScope)+len(d.RemovedScope)+len(d.AddedOutOfScope)+len(d.RemovedOutOfScope) == 0
+}
+
+// TargetVersion is a snapshot of what a target's scope looked like from
+// Created until the next version.
+type TargetVersion struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Target     primitive.ObjectID `json:"target"`
+	Version    int                `json:"version"`
+	Bounty     *bool              `json:"bounty"`
+	Scope      []string           `json:"scope"`
+	OutOfScope []string           `json:"outOfScope" bson:"outOfScope"`
+	Diff       ScopeDiff          `json:"diff"`
+	Origin     string             `json:"origin"`
+	Created    time.Time          `json:"created"`
+}
+
+// NewTargetVersion snapshots t as the version following previous, which is
+// nil for the first one. Origin tells where the change came from.
+func NewTargetVersion(previous *TargetVersion, t *Target, origin string) *TargetVersion {
+	version := &TargetVersion{
+		Target:     t.ID,
+		Version:    1,
+		Bounty:     t.Bounty,
+		Scope:      t.Scope,
+		OutOfScope: t.OutOfScope,
+		Origin:     origin,
+		Created:    time.Now(),
+	}
+
+	if previous == nil {
+		version.Diff = CompareVersions(&TargetVersion{}, version)
+	} else {
+		version.Version = previous.Version + 1
+		version.Diff = CompareVersions(previous, version)
+	}
+
+	return version
+}
+
+// CompareVersions returns the changes needed to go from one version to another.
+func CompareVersions(from *TargetVersion, to *TargetVersion) ScopeDiff {
+	diff := ScopeDiff{}
+
+	diff.AddedScope, diff.RemovedScope = DiffScope(from.Scope, to.Scope)
+	diff.AddedOutOfScope, diff.RemovedOutOfScope = DiffScope(from.OutOfScope, to.OutOfScope)
+
+	if to.Bounty != nil && (from.Bounty == nil || *from.Bounty != *to.Bounty) {
+		diff.Bounty = to.Bounty
+	}
+
+	return diff
+}
@@ -0,0 +1,515 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskTypes lists the task types a job definition can refer to.
+var TaskTypes = []string{
+	"subdomain-enumeration",
+	"dns-resolve",
+	"dns-resolve-all",
+	"http-discovery",
+	"http-discovery-all",
+	"update-nuclei",
+	"run-new-templates",
+	"scope-sync",
+}
+
+// EventKinds lists the events a job definition can run on.
+var EventKinds = []string{
+	"subdomain-created",
+	"dns-activated",
+	"http-service-created",
+}
+
+// RetryClasses lists the kinds of errors a retry policy can retry.
+var RetryClasses = []string{"exec", "timeout", "db"}
+
+// RetryPolicy says how a failed task is tried again. MaxAttempts counts the
+// first try as well, Backoff is the delay before the first retry and doubles
+// on every other one, up to MaxBackoff. On limits retries to some kinds of
+// errors, all of them by default.
+type RetryPolicy struct {
+	MaxAttempts int      `json:"maxAttempts" bson:"maxAttempts"`
+	Backoff     string   `json:"backoff,omitempty" bson:"backoff,omitempty"`
+	MaxBackoff  string   `json:"maxBackoff,omitempty" bson:"maxBackoff,omitempty"`
+	On          []string `json:"on,omitempty" bson:"on,omitempty"`
+}
+
+func (r *RetryPolicy) validate() string {
+	if r.MaxAttempts < 0 {
+		return "maxAttempts can't be negative."
+	}
+
+	for _, value := range []string{r.Backoff, r.MaxBackoff} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return "backoff and maxBackoff must be durations, e.g. 30s."
+		}
+	}
+
+	for _, class := range r.On {
+		known := false
+		for _, retryClass := range RetryClasses {
+			known = known || class == retryClass
+		}
+
+		if !known {
+			return "on must be some of " + strings.Join(RetryClasses, ", ") + "."
+		}
+	}
+
+	return ""
+}
+
+func (r *RetryPolicy) BackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(r.Backoff)
+	return d
+}
+
+func (r *RetryPolicy) MaxBackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(r.MaxBackoff)
+	return d
+}
+
+// TaskDefinition describes a task, Retry overrides the job's retry policy.
+// Concurrency is how many units of work (e.g. root domains) the task works on
+// at once, for the tasks that split their work, one by default. Timeout is the
+// task's own budget within the job's, so a slow task can't eat up the time
+// of the ones after it, tasks without one share what's left, see
+// JobDefinition.StepBudgets. Tools, if set, are run through their adapters
+// instead of the script at ScriptPath, see TaskTools.
+type TaskDefinition struct {
+	Type        string       `json:"type"`
+	ScriptPath  string       `json:"scriptPath" bson:"scriptPath"`
+	Tools       []string     `json:"tools,omitempty" bson:"tools,omitempty"`
+	Selector    string       `json:"selector"`
+	Retry       *RetryPolicy `json:"retry,omitempty" bson:"retry,omitempty"`
+	Concurrency int          `json:"concurrency,omitempty" bson:"concurrency,omitempty"`
+	Timeout     string       `json:"timeout,omitempty" bson:"timeout,omitempty"`
+}
+
+func (t *TaskDefinition) TimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(t.Timeout)
+	return d
+}
+
+// JobDefinition describes a scheduled job. A job is either a main task with
+// sub tasks running after it in order, whatever the previous one did, or a
+// graph of Steps.
+//
+// A job runs either every Interval, on a Cron expression (five fields, or six
+// with seconds, CRON_TZ= prefixes are honored) or every day at the DailyAt
+// times ("02:30" or "02:30:15", server time). Jitter delays every run by a
+// random duration up to its value. Interval jobs start right away on boot
+// unless StartImmediately is false, the others wait for their first slot
+// unless it's true.
+//
+// A job with On also runs shortly after one of those events, on just the
+// assets they carry. Such a job may go without a schedule.
+//
+// DefaultRetry applies to every task of the job without a Retry of its own.
+type JobDefinition struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name             string             `json:"name"`
+	TaskDefinition   `bson:",inline"`
+	Interval         string           `json:"interval,omitempty" bson:"interval,omitempty"`
+	Cron             string           `json:"cron,omitempty" bson:"cron,omitempty"`
+	DailyAt          []string         `json:"dailyAt,omitempty" bson:"dailyAt,omitempty"`
+	Jitter           string           `json:"jitter,omitempty" bson:"jitter,omitempty"`
+	StartImmediately *bool            `json:"startImmediately,omitempty" bson:"startImmediately,omitempty"`
+	Timeout          string           `json:"timeout"`
+	Active           bool             `json:"active"`
+	SubTasks         []TaskDefinition `json:"subTasks" bson:"subTasks"`
+	Steps            []StepDefinition `json:"steps,omitempty" bson:"steps,omitempty"`
+	On               []string         `json:"on,omitempty" bson:"on,omitempty"`
+	DefaultRetry     *RetryPolicy     `json:"defaultRetry,omitempty" bson:"defaultRetry,omitempty"`
+}
+
+const (
+	// StepAlways runs a step once its dependencies are done, however they
+	// ended.
+	StepAlways = "always"
+	// StepOnSuccess runs a step only if all of its dependencies succeeded,
+	// it's the default.
+	StepOnSuccess = "success"
+	// StepOnNew runs a step only if all of its dependencies succeeded and at
+	// least one of them found something new.
+	StepOnNew = "new"
+)
+
+// StepDefinition is a step of a job's graph. A step starts once every step
+// it depends on is done and its When condition holds, steps sharing their
+// dependencies run side by side.
+type StepDefinition struct {
+	Name           string `json:"name"`
+	TaskDefinition `bson:",inline"`
+	DependsOn      []string `json:"dependsOn,omitempty" bson:"dependsOn,omitempty"`
+	When           string   `json:"when,omitempty" bson:"when,omitempty"`
+}
+
+// Graph returns the job's steps, a main task with sub tasks is turned into a
+// chain of steps named after their types that always run.
+func (j *JobDefinition) Graph() []StepDefinition {
+	if len(j.Steps) != 0 {
+		return j.Steps
+	}
+
+	steps := []StepDefinition{{Name: j.Type, TaskDefinition: j.TaskDefinition}}
+	seen := map[string]int{j.Type: 1}
+
+	for _, task := range j.SubTasks {
+		name := task.Type
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, seen[name])
+		}
+
+		steps = append(steps, StepDefinition{
+			Name:           name,
+			TaskDefinition: task,
+			DependsOn:      []string{steps[len(steps)-1].Name},
+			When:           StepAlways,
+		})
+	}
+
+	return steps
+}
+
+func (j *JobDefinition) Validate() jsonErrors {
+	errors := map[string]map[string]string{}
+
+	if strings.TrimSpace(j.Name) == "" {
+		errors["name"] = map[string]string{"error": "required"}
+	}
+
+	schedules := 0
+	if j.Interval != "" {
+		schedules++
+		if interval, err := time.ParseDuration(j.Interval); err != nil || interval <= 0 {
+			errors["interval"] = map[string]string{"error": "must be a positive duration, e.g. 48h."}
+		}
+	}
+
+	if j.Cron != "" {
+		schedules++
+		if _, err := cronParser.Parse(j.Cron); err != nil {
+			errors["cron"] = map[string]string{"error": err.Error()}
+		}
+	}
+
+	if len(j.DailyAt) != 0 {
+		schedules++
+		for _, at := range j.DailyAt {
+			if _, err := ParseDailyTime(at); err != nil {
+				errors["dailyAt"] = map[string]string{"error": err.Error()}
+				break
+			}
+		}
+	}
+
+	if schedules > 1 || schedules == 0 && len(j.On) == 0 {
+		errors["schedule"] = map[string]string{"error": "exactly one of interval, cron or dailyAt is required."}
+	}
+
+	for _, kind := range j.On {
+		known := false
+		for _, eventKind := range EventKinds {
+			known = known || kind == eventKind
+		}
+
+		if !known {
+			errors["on"] = map[string]string{"error": "invalid value, must be one of " + strings.Join(EventKinds, ", ") + "."}
+			break
+		}
+	}
+
+	if j.Jitter != "" {
+		if jitter, err := time.ParseDuration(j.Jitter); err != nil || jitter < 0 {
+			errors["jitter"] = map[string]string{"error": "must be a duration, e.g. 15m."}
+		}
+	}
+
+	if timeout, err := time.ParseDuration(j.Timeout); err != nil || timeout <= 0 {
+		errors["timeout"] = map[string]string{"error": "must be a positive duration, e.g. 2h."}
+	}
+
+	if j.DefaultRetry != nil {
+		if err := j.DefaultRetry.validate(); err != "" {
+			errors["defaultRetry"] = map[string]string{"error": err}
+		}
+	}
+
+	if len(j.Steps) != 0 {
+		if j.Type != "" || len(j.SubTasks) != 0 {
+			errors["steps"] = map[string]string{"error": "can't be combined with type or subTasks."}
+		} else if err := validateSteps(j.Steps); err != "" {
+			errors["steps"] = map[string]string{"error": err}
+		}
+
+		j.checkBudgets(errors)
+		return errors
+	}
+
+	if err := j.TaskDefinition.validate(); err != "" {
+		errors["type"] = map[string]string{"error": err}
+	}
+
+	for _, task := range j.SubTasks {
+		if err := task.validate(); err != "" {
+			errors["subTasks"] = map[string]string{"error": err}
+			break
+		}
+	}
+
+	j.checkBudgets(errors)
+	return errors
+}
+
+func (t *TaskDefinition) validate() string {
+	known := false
+	for _, taskType := range TaskTypes {
+		if t.Type == taskType {
+			known = true
+			break
+		}
+	}
+
+	if !known {
+		return "invalid value, must be one of " + strings.Join(TaskTypes, ", ") + "."
+	}
+
+	if _, err := ParseLabelSelector(t.Selector); err != nil {
+		return err.Error()
+	}
+
+	if t.Concurrency < 0 {
+		return "concurrency can't be negative."
+	}
+
+	if t.Timeout != "" {
+		if timeout, err := time.ParseDuration(t.Timeout); err != nil || timeout <= 0 {
+			return "timeout must be a positive duration, e.g. 30m."
+		}
+	}
+
+	if t.Retry != nil {
+		if err := t.Retry.validate(); err != "" {
+			return "retry: " + err
+		}
+	}
+
+	if err := t.validateTools(); err != "" {
+		return "tools: " + err
+	}
+
+	return ""
+}
+
+func validateSteps(steps []StepDefinition) string {
+	byName := map[string]*StepDefinition{}
+	for i, step := range steps {
+		if strings.TrimSpace(step.Name) == "" {
+			return "every step needs a name."
+		}
+		if _, ok := byName[step.Name]; ok {
+			return fmt.Sprintf("step %s is defined twice.", step.Name)
+		}
+		byName[step.Name] = &steps[i]
+	}
+
+	for _, step := range steps {
+		if err := step.validate(); err != "" {
+			return fmt.Sprintf("step %s: %s", step.Name, err)
+		}
+
+		switch step.When {
+		case "", StepAlways, StepOnSuccess, StepOnNew:
+		default:
+			return fmt.Sprintf("step %s: when must be one of %s, %s, %s.", step.Name, StepAlways, StepOnSuccess, StepOnNew)
+		}
+
+		for _, dep := range step.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Sprintf("step %s depends on unknown step %s.", step.Name, dep)
+			}
+		}
+	}
+
+	// Depth first walk, a step met again while its own dependencies are
+	// still being walked closes a cycle.
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := map[string]int{}
+
+	var walk func(name string) string
+	walk = func(name string) string {
+		switch marks[name] {
+		case visiting:
+			return fmt.Sprintf("steps form a cycle through %s.", name)
+		case visited:
+			return ""
+		}
+
+		marks[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if err := walk(dep); err != "" {
+				return err
+			}
+		}
+		marks[name] = visited
+
+		return ""
+	}
+
+	for _, step := range steps {
+		if err := walk(step.Name); err != "" {
+			return err
+		}
+	}
+
+	return ""
+}
+
+func (j *JobDefinition) IntervalDuration() time.Duration {
+	d, _ := time.ParseDuration(j.Interval)
+	return d
+}
+
+func (j *JobDefinition) JitterDuration() time.Duration {
+	d, _ := time.ParseDuration(j.Jitter)
+	return d
+}
+
+// HasSchedule reports whether the job runs on a schedule, and not only on
+// events.
+func (j *JobDefinition) HasSchedule() bool {
+	return j.Interval != "" || j.Cron != "" || len(j.DailyAt) != 0
+}
+
+// StartsImmediately reports whether the job should run as soon as it's
+// scheduled, instead of waiting for its first slot.
+func (j *JobDefinition) StartsImmediately() bool {
+	if j.StartImmediately != nil {
+		return *j.StartImmediately
+	}
+
+	return j.Interval != ""
+}
+
+func (j *JobDefinition) TimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(j.Timeout)
+	return d
+}
+
+// StepBudgets returns how long each step of the job may run, counted from
+// its own start. Steps without a timeout of their own evenly share what the
+// others leave of the job's timeout, so their timeouts never add up to more
+// than it.
+func (j *JobDefinition) StepBudgets() map[string]time.Duration {
+	graph := j.Graph()
+	budgets := make(map[string]time.Duration, len(graph))
+
+	left, shared := j.TimeoutDuration(), 0
+	for _, step := range graph {
+		if timeout := step.TimeoutDuration(); timeout != 0 {
+			budgets[step.Name] = timeout
+			left -= timeout
+		} else {
+			shared++
+		}
+	}
+
+	for _, step := range graph {
+		if _, ok := budgets[step.Name]; !ok {
+			budgets[step.Name] = left / time.Duration(shared)
+		}
+	}
+
+	return budgets
+}
+
+// checkBudgets makes sure the timeouts of the steps fit in the job's, once
+// everything else is valid.
+func (j *JobDefinition) checkBudgets(errors jsonErrors) {
+	if len(errors) != 0 {
+		return
+	}
+
+	for _, budget := range j.StepBudgets() {
+		if budget <= 0 {
+			errors["timeout"] = map[string]string{"error": "the timeouts of the steps must add up to less than the job's, leaving time for the steps without one."}
+			return
+		}
+	}
+}
+
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// DailyTime is a time of day, as used by JobDefinition.DailyAt.
+type DailyTime struct {
+	Hours   uint
+	Minutes uint
+	Seconds uint
+}
+
+func ParseDailyTime(value string) (DailyTime, error) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return DailyTime{uint(t.Hour()), uint(t.Minute()), uint(t.Second())}, nil
+		}
+	}
+
+	return DailyTime{}, fmt.Errorf("invalid time %q, expected HH:MM or HH:MM:SS", value)
+}
+
+const (
+	RunPending   = "pending"
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+	RunTimedOut  = "timed-out"
+)
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerEvent    = "event"
+	// TriggerDue runs are started for targets whose schedule came due
+	// between the job's own runs.
+	TriggerDue = "due"
+)
+
+// JobRun records a single run of a job, or of one of its tasks when Parent
+// points at the run of the job it belongs to.
+type JobRun struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Job      primitive.ObjectID  `json:"job"`
+	Parent   *primitive.ObjectID `json:"parent,omitempty" bson:"parent,omitempty"`
+	Task     string              `json:"task"`
+	Trigger  string              `json:"trigger"`
+	Target   string              `json:"target,omitempty" bson:"target,omitempty"`
+	Status   string              `json:"status"`
+	Error    string              `json:"error,omitempty" bson:"error,omitempty"`
+	Attempts int                 `json:"attempts,omitempty" bson:"attempts,omitempty"`
+	Input    int                 `json:"input"`
+	New      int                 `json:"new"`
+	Skipped  []string            `json:"skipped,omitempty" bson:"skipped,omitempty"`
+	Failed   []string            `json:"failed,omitempty" bson:"failed,omitempty"`
+	Started  time.Time           `json:"started"`
+	Finished *time.Time          `json:"finished"`
+
+	Tasks []JobRun `json:"tasks,omitempty" bson:"-"`
+}
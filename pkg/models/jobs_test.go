@@ -0,0 +1,185 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepBudgets(t *testing.T) {
+	def := JobDefinition{
+		Name:           "enumeration",
+		TaskDefinition: TaskDefinition{Type: "subdomain-enumeration"},
+		Interval:       "48h",
+		Timeout:        "2h",
+		SubTasks: []TaskDefinition{
+			{Type: "dns-resolve", Timeout: "15m"},
+			{Type: "http-discovery", Timeout: "15m"},
+		},
+	}
+
+	want := map[string]time.Duration{
+		"subdomain-enumeration": 90 * time.Minute,
+		"dns-resolve":           15 * time.Minute,
+		"http-discovery":        15 * time.Minute,
+	}
+
+	budgets := def.StepBudgets()
+	for name, budget := range want {
+		if budgets[name] != budget {
+			t.Errorf("budget of %s = %s, want %s", name, budgets[name], budget)
+		}
+	}
+
+	if errs := def.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	def.SubTasks[0].Timeout = "1h45m"
+	if errs := def.Validate(); errs["timeout"] == nil {
+		t.Errorf("Validate() = %v, want a timeout error for budgets adding up to the job's", errs)
+	}
+
+	def.SubTasks[0].Timeout = "3h"
+	if errs := def.Validate(); errs["timeout"] == nil {
+		t.Errorf("Validate() = %v, want a timeout error for budgets over the job's", errs)
+	}
+}
+
+func TestParseDailyTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  DailyTime
+	}{
+		{value: "02:30", want: DailyTime{Hours: 2, Minutes: 30}},
+		{value: "02:30:15", want: DailyTime{Hours: 2, Minutes: 30, Seconds: 15}},
+		{value: "00:00", want: DailyTime{}},
+		{value: "23:59:59", want: DailyTime{Hours: 23, Minutes: 59, Seconds: 59}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDailyTime(test.value)
+		if err != nil {
+			t.Errorf("ParseDailyTime(%q) failed: %v", test.value, err)
+		} else if got != test.want {
+			t.Errorf("ParseDailyTime(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"", "2:30pm", "24:00", "12:60", "12:30:60", "12", "12:30:00:00", " 12:30"} {
+		if _, err := ParseDailyTime(value); err == nil {
+			t.Errorf("ParseDailyTime(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestCronParser(t *testing.T) {
+	valid := []string{
+		"0 3 * * *",
+		"30 0 3 * * *",
+		"*/15 * * * *",
+		"0 9 * * MON-FRI",
+		"CRON_TZ=Asia/Tehran 0 3 * * *",
+		"@daily",
+		"@every 6h",
+	}
+	for _, spec := range valid {
+		if _, err := cronParser.Parse(spec); err != nil {
+			t.Errorf("cronParser.Parse(%q) failed: %v", spec, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * *",
+		"0 0 0 3 * * *",
+		"61 * * * *",
+		"0 25 * * *",
+		"0 3 * * FUNDAY",
+		"CRON_TZ=Mars/Olympus 0 3 * * *",
+		"@fortnightly",
+	}
+	for _, spec := range invalid {
+		if _, err := cronParser.Parse(spec); err == nil {
+			t.Errorf("cronParser.Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    JobDefinition
+		fields []string
+	}{
+		{name: "interval", def: JobDefinition{Interval: "48h"}},
+		{name: "cron", def: JobDefinition{Cron: "0 3 * * *"}},
+		{name: "daily", def: JobDefinition{DailyAt: []string{"02:30", "14:30:15"}}},
+		{name: "events only", def: JobDefinition{On: []string{EventKinds[0]}}},
+		{name: "jitter", def: JobDefinition{Interval: "1h", Jitter: "15m"}},
+		{name: "zero jitter", def: JobDefinition{Interval: "1h", Jitter: "0s"}},
+		{name: "no schedule", def: JobDefinition{}, fields: []string{"schedule"}},
+		{name: "two schedules", def: JobDefinition{Interval: "1h", Cron: "0 3 * * *"}, fields: []string{"schedule"}},
+		{name: "daily and cron", def: JobDefinition{Cron: "@daily", DailyAt: []string{"02:30"}}, fields: []string{"schedule"}},
+		{name: "bad interval", def: JobDefinition{Interval: "-1h"}, fields: []string{"interval"}},
+		{name: "bad cron", def: JobDefinition{Cron: "every day"}, fields: []string{"cron"}},
+		{name: "bad daily", def: JobDefinition{DailyAt: []string{"02:30", "25:00"}}, fields: []string{"dailyAt"}},
+		{name: "bad jitter", def: JobDefinition{Interval: "1h", Jitter: "soon"}, fields: []string{"jitter"}},
+		{name: "negative jitter", def: JobDefinition{Interval: "1h", Jitter: "-5m"}, fields: []string{"jitter"}},
+	}
+
+	for _, test := range tests {
+		def := test.def
+		def.Name = "job"
+		def.Type = "subdomain-enumeration"
+		def.Timeout = "1h"
+
+		errs := def.Validate()
+		for _, field := range test.fields {
+			if errs[field] == nil {
+				t.Errorf("%s: Validate() = %v, want an error on %s", test.name, errs, field)
+			}
+		}
+		if len(test.fields) == 0 && len(errs) != 0 {
+			t.Errorf("%s: Validate() = %v, want no errors", test.name, errs)
+		}
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	step := func(name string, deps ...string) StepDefinition {
+		return StepDefinition{Name: name, TaskDefinition: TaskDefinition{Type: "dns-resolve"}, DependsOn: deps}
+	}
+
+	tests := []struct {
+		name  string
+		steps []StepDefinition
+		want  string
+	}{
+		{name: "single", steps: []StepDefinition{step("a")}},
+		{name: "chain", steps: []StepDefinition{step("a"), step("b", "a"), step("c", "b")}},
+		{name: "diamond", steps: []StepDefinition{step("a"), step("b", "a"), step("c", "a"), step("d", "b", "c")}},
+		{name: "declared after use", steps: []StepDefinition{step("b", "a"), step("a")}},
+		{name: "self loop", steps: []StepDefinition{step("a", "a")}, want: "cycle"},
+		{name: "indirect cycle", steps: []StepDefinition{step("a", "c"), step("b", "a"), step("c", "b")}, want: "cycle"},
+		{name: "cycle off the root", steps: []StepDefinition{step("root"), step("a", "root", "b"), step("b", "a")}, want: "cycle"},
+		{name: "unknown dependency", steps: []StepDefinition{step("a"), step("b", "nope")}, want: "unknown step nope"},
+		{name: "duplicate names", steps: []StepDefinition{step("a"), step("b", "a"), step("a")}, want: "defined twice"},
+		{name: "unnamed", steps: []StepDefinition{step("a"), step(" ")}, want: "needs a name"},
+		{
+			name:  "bad when",
+			steps: []StepDefinition{step("a"), {Name: "b", TaskDefinition: TaskDefinition{Type: "dns-resolve"}, When: "sometimes"}},
+			want:  "when must be",
+		},
+	}
+
+	for _, test := range tests {
+		got := validateSteps(test.steps)
+		switch {
+		case test.want == "" && got != "":
+			t.Errorf("%s: validateSteps = %q, want no error", test.name, got)
+		case test.want != "" && !strings.Contains(got, test.want):
+			t.Errorf("%s: validateSteps = %q, want an error with %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// LabelSelector picks targets by their labels. It's written as a comma
+// separated list where "vdp" requires the label and "!vdp" forbids it, so
+// "high-payout,!private" selects public targets with high payouts. An empty
+// selector matches every target.
+type LabelSelector struct {
+	required  []string
+	forbidden []string
+}
+
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var l LabelSelector
+
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		label, forbidden := strings.CutPrefix(part, "!")
+		if err := validateLabel(label); err != nil {
+			return LabelSelector{}, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+
+		if forbidden {
+			l.forbidden = append(l.forbidden, label)
+		} else {
+			l.required = append(l.required, label)
+		}
+	}
+
+	return l, nil
+}
+
+func validateLabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("empty label")
+	}
+	if strings.ContainsAny(label, ", !") {
+		return fmt.Errorf("label %q can't contain commas, spaces or \"!\"", label)
+	}
+
+	return nil
+}
+
+func (l LabelSelector) IsEmpty() bool {
+	return len(l.required) == 0 && len(l.forbidden) == 0
+}
+
+func (l LabelSelector) Matches(labels []string) bool {
+	has := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		has[label] = true
+	}
+
+	for _, label := range l.required {
+		if !has[label] {
+			return false
+		}
+	}
+	for _, label := range l.forbidden {
+		if has[label] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter returns the query selecting matching documents of the targets collection.
+func (l LabelSelector) Filter() bson.M {
+	labels := bson.M{}
+	if len(l.required) != 0 {
+		labels["$all"] = l.required
+	}
+	if len(l.forbidden) != 0 {
+		labels["$nin"] = l.forbidden
+	}
+
+	if len(labels) == 0 {
+		return bson.M{}
+	}
+
+	return bson.M{"labels": labels}
+}
+
+func (l LabelSelector) String() string {
+	parts := append([]string{}, l.required...)
+	for _, label := range l.forbidden {
+		parts = append(parts, "!"+label)
+	}
+
+	return strings.Join(parts, ",")
+}
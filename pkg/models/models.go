@@ -10,13 +10,35 @@ import (
 
 type jsonErrors map[string]map[string]string
 
+// Target is a bug bounty program. Scope and OutOfScope entries follow the
+// grammar described on ScopeRule, a bare domain means that host only so
+// programs that want their subdomains enumerated have to use a wildcard.
+// Scopes stored before that grammar are rewritten once by LegacyScope.
 type Target struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name       string             `json:"name"`
 	Bounty     *bool              `json:"bounty"`
 	Scope      []string           `json:"scope"`
 	OutOfScope []string           `json:"outOfScope" bson:"outOfScope"`
 	Source     string             `json:"source"`
+	Handle     string             `json:"handle"`
+	Labels     []string           `json:"labels"`
+
+	// Enabled is nil for targets created before it existed, they count as
+	// enabled. Higher priority targets are enumerated first, the ones without
+	// one last. Both are pointers so edits leaving them out keep them as is.
+	Enabled        *bool           `json:"enabled"`
+	Priority       *int            `json:"priority" bson:"priority,omitempty"`
+	Schedule       *TargetSchedule `json:"schedule,omitempty" bson:"schedule,omitempty"`
+	LastEnumerated *time.Time      `json:"lastEnumerated" bson:"lastEnumerated"`
+	LastResolved   *time.Time      `json:"lastResolved" bson:"lastResolved"`
+	LastScanned    *time.Time      `json:"lastScanned" bson:"lastScanned"`
+
+	// ScanWindow is when the program allows active testing, nil if it has no
+	// restrictions. Passive enumeration isn't bound by it.
+	ScanWindow *ScanWindow `json:"scanWindow,omitempty" bson:"scanWindow,omitempty"`
+
+	matcher *ScopeMatcher
 }
 
 func (t *Target) Validate() jsonErrors {
@@ -34,6 +56,33 @@ func (t *Target) Validate() jsonErrors {
 		errors["scope"] = map[string]string{"error": "required."}
 	}
 
+	if _, err := parseScopeRules(t.Scope); err != nil && errors["scope"] == nil {
+		errors["scope"] = map[string]string{"error": err.Error()}
+	}
+
+	if _, err := parseScopeRules(t.OutOfScope); err != nil {
+		errors["outOfScope"] = map[string]string{"error": err.Error()}
+	}
+
+	for _, label := range t.Labels {
+		if err := validateLabel(label); err != nil {
+			errors["labels"] = map[string]string{"error": err.Error()}
+			break
+		}
+	}
+
+	if t.Schedule != nil {
+		if err := t.Schedule.validate(); err != "" {
+			errors["schedule"] = map[string]string{"error": err}
+		}
+	}
+
+	if t.ScanWindow != nil {
+		if err := t.ScanWindow.validate(); err != "" {
+			errors["scanWindow"] = map[string]string{"error": err}
+		}
+	}
+
 	if t.Source != "hackerone" && t.Source != "bugcrowd" && t.Source != "integrity" && t.Source != "yeswehack" {
 		errors["source"] = map[string]string{"error": "invalid value."}
 	}
@@ -41,11 +90,36 @@ func (t *Target) Validate() jsonErrors {
 	return errors
 }
 
+func (t *Target) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+// Matcher returns the scope matcher built from the target's scope entries.
+func (t *Target) Matcher() (*ScopeMatcher, error) {
+	if t.matcher == nil {
+		matcher, err := NewScopeMatcher(t.Scope, t.OutOfScope)
+		if err != nil {
+			return nil, err
+		}
+		t.matcher = matcher
+	}
+
+	return t.matcher, nil
+}
+
+// Exclusion marks an asset that was found but must not be touched by any job
+// because it is out of its target's scope.
+type Exclusion struct {
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+}
+
 type Subdomain struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	Target    primitive.ObjectID
 	Subdomain string
 	Dns       *Dns
+	Excluded  *Exclusion `bson:"excluded,omitempty"`
 	Created   time.Time
 }
 
@@ -59,7 +133,8 @@ type HttpService struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	Subdomain primitive.ObjectID
 	Host      string
-	IsActive  bool `bson:"isActive"`
+	IsActive  bool       `bson:"isActive"`
+	Excluded  *Exclusion `bson:"excluded,omitempty"`
 	Created   *time.Time
 	Updated   time.Time
 }
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScanWindow restricts when a target's assets may be tested actively, e.g.
+// probed for http services or scanned with nuclei. Hours are ranges like
+// "22:00-06:00" in Timezone, UTC if it's empty, none meaning any hour, and
+// Blackout are dates like "2024-12-24" on which they aren't tested at any
+// hour. Passive work like enumerating subdomains ignores the window.
+type ScanWindow struct {
+	Hours    []string `json:"hours,omitempty" bson:"hours,omitempty"`
+	Timezone string   `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	Blackout []string `json:"blackout,omitempty" bson:"blackout,omitempty"`
+}
+
+// hourRange is a range of minutes of the day, it wraps around midnight if it
+// ends before it starts.
+type hourRange struct {
+	start, end int
+}
+
+func parseHourRange(value string) (hourRange, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return hourRange{}, fmt.Errorf("%q isn't a range like 09:00-17:00", value)
+	}
+
+	var r hourRange
+	for _, bound := range []struct {
+		value   string
+		minutes *int
+	}{{from, &r.start}, {to, &r.end}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound.value))
+		if err != nil {
+			return hourRange{}, fmt.Errorf("%q isn't a range like 09:00-17:00", value)
+		}
+		*bound.minutes = t.Hour()*60 + t.Minute()
+	}
+
+	if r.start == r.end {
+		return hourRange{}, fmt.Errorf("%q is empty", value)
+	}
+
+	return r, nil
+}
+
+func (r hourRange) contains(minute int) bool {
+	if r.start < r.end {
+		return r.start <= minute && minute < r.end
+	}
+	return minute >= r.start || minute < r.end
+}
+
+func (w *ScanWindow) validate() string {
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Sprintf("unknown timezone %q.", w.Timezone)
+	}
+
+	for _, hours := range w.Hours {
+		if _, err := parseHourRange(hours); err != nil {
+			return err.Error() + "."
+		}
+	}
+
+	for _, date := range w.Blackout {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return fmt.Sprintf("blackout date %q isn't like 2024-12-24.", date)
+		}
+	}
+
+	return ""
+}
+
+// Open reports whether the window allows testing at now, and why not if it
+// doesn't. A nil window is always open.
+func (w *ScanWindow) Open(now time.Time) (bool, string) {
+	if w == nil {
+		return true, ""
+	}
+
+	location, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return false, fmt.Sprintf("unknown timezone %q", w.Timezone)
+	}
+	local := now.In(location)
+
+	today := local.Format(time.DateOnly)
+	for _, date := range w.Blackout {
+		if date == today {
+			return false, fmt.Sprintf("%s is a blackout date", today)
+		}
+	}
+
+	if len(w.Hours) == 0 {
+		return true, ""
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	for _, hours := range w.Hours {
+		if r, err := parseHourRange(hours); err == nil && r.contains(minute) {
+			return true, ""
+		}
+	}
+
+	return false, fmt.Sprintf("%s %s is outside of %s", local.Format("15:04"), location, strings.Join(w.Hours, ", "))
+}
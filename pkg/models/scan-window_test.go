@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHourRange(t *testing.T) {
+	tests := []struct {
+		value  string
+		minute int
+		want   bool
+	}{
+		{value: "09:00-17:00", minute: 9 * 60, want: true},
+		{value: "09:00-17:00", minute: 16*60 + 59, want: true},
+		{value: "09:00-17:00", minute: 17 * 60, want: false},
+		{value: "09:00-17:00", minute: 8*60 + 59, want: false},
+		{value: "22:00-06:00", minute: 23 * 60, want: true},
+		{value: "22:00-06:00", minute: 0, want: true},
+		{value: "22:00-06:00", minute: 5*60 + 59, want: true},
+		{value: "22:00-06:00", minute: 6 * 60, want: false},
+		{value: "22:00-06:00", minute: 12 * 60, want: false},
+		{value: "22:00-06:00", minute: 21*60 + 59, want: false},
+		{value: " 23:30 - 00:15 ", minute: 23*60 + 45, want: true},
+		{value: " 23:30 - 00:15 ", minute: 10, want: true},
+		{value: " 23:30 - 00:15 ", minute: 15, want: false},
+	}
+
+	for _, test := range tests {
+		r, err := parseHourRange(test.value)
+		if err != nil {
+			t.Errorf("parseHourRange(%q) failed: %v", test.value, err)
+			continue
+		}
+
+		if got := r.contains(test.minute); got != test.want {
+			t.Errorf("%q contains %02d:%02d = %t, want %t", test.value, test.minute/60, test.minute%60, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"", "09:00", "9-17", "09:00-25:00", "10:00-10:00"} {
+		if _, err := parseHourRange(value); err == nil {
+			t.Errorf("parseHourRange(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestScanWindowOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *ScanWindow
+		now    string
+		want   bool
+	}{
+		{name: "no window", window: nil, now: "2024-06-01T12:00:00Z", want: true},
+		{name: "any hour", window: &ScanWindow{}, now: "2024-06-01T12:00:00Z", want: true},
+		{name: "inside hours", window: &ScanWindow{Hours: []string{"09:00-17:00"}}, now: "2024-06-01T12:00:00Z", want: true},
+		{name: "outside hours", window: &ScanWindow{Hours: []string{"09:00-17:00"}}, now: "2024-06-01T18:00:00Z", want: false},
+		{name: "second range", window: &ScanWindow{Hours: []string{"09:00-10:00", "17:00-19:00"}}, now: "2024-06-01T18:00:00Z", want: true},
+		{name: "overnight", window: &ScanWindow{Hours: []string{"22:00-06:00"}}, now: "2024-06-01T02:00:00Z", want: true},
+		{
+			name:   "timezone",
+			window: &ScanWindow{Hours: []string{"09:00-17:00"}, Timezone: "Asia/Tehran"},
+			now:    "2024-06-01T04:00:00Z", // 07:30 in Tehran
+			want:   false,
+		},
+		{
+			name:   "timezone inside",
+			window: &ScanWindow{Hours: []string{"09:00-17:00"}, Timezone: "Asia/Tehran"},
+			now:    "2024-06-01T06:00:00Z", // 09:30 in Tehran
+			want:   true,
+		},
+		{name: "blackout", window: &ScanWindow{Blackout: []string{"2024-06-01"}}, now: "2024-06-01T12:00:00Z", want: false},
+		{name: "blackout other day", window: &ScanWindow{Blackout: []string{"2024-06-02"}}, now: "2024-06-01T12:00:00Z", want: true},
+		{
+			name:   "blackout in timezone",
+			window: &ScanWindow{Blackout: []string{"2024-06-02"}, Timezone: "Asia/Tehran"},
+			now:    "2024-06-01T21:00:00Z", // already the 2nd in Tehran
+			want:   false,
+		},
+		{name: "unknown timezone", window: &ScanWindow{Timezone: "Mars/Olympus"}, now: "2024-06-01T12:00:00Z", want: false},
+	}
+
+	for _, test := range tests {
+		now, err := time.Parse(time.RFC3339, test.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, reason := test.window.Open(now)
+		if got != test.want {
+			t.Errorf("%s: Open = %t (%s), want %t", test.name, got, reason, test.want)
+		}
+		if !got && reason == "" {
+			t.Errorf("%s: closed without a reason", test.name)
+		}
+	}
+}
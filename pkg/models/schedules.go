@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ScheduleEnumeration = "enumeration"
+	ScheduleResolution  = "resolution"
+	ScheduleScanning    = "scanning"
+)
+
+// TargetSchedule says how often a target's subdomains are enumerated,
+// resolved and scanned with nuclei. Empty intervals fall back to the ones of
+// the target's class, and a target without any is handled on every run of
+// the jobs doing it.
+type TargetSchedule struct {
+	Enumeration string `json:"enumeration,omitempty" bson:"enumeration,omitempty"`
+	Resolution  string `json:"resolution,omitempty" bson:"resolution,omitempty"`
+	Scanning    string `json:"scanning,omitempty" bson:"scanning,omitempty"`
+}
+
+func (s *TargetSchedule) validate() string {
+	for _, value := range []string{s.Enumeration, s.Resolution, s.Scanning} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return "intervals must be positive durations, e.g. 12h."
+		}
+	}
+
+	return ""
+}
+
+// Interval returns the schedule's interval for kind, zero if it has none.
+func (s *TargetSchedule) Interval(kind string) time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	var value string
+	switch kind {
+	case ScheduleEnumeration:
+		value = s.Enumeration
+	case ScheduleResolution:
+		value = s.Resolution
+	case ScheduleScanning:
+		value = s.Scanning
+	}
+
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+// ScheduleClasses holds the schedules of bounty and non bounty targets, for
+// targets without a schedule of their own. It's stored in the config
+// collection.
+type ScheduleClasses struct {
+	Bounty   TargetSchedule `json:"bounty"`
+	NoBounty TargetSchedule `json:"noBounty" bson:"noBounty"`
+}
+
+func (c *ScheduleClasses) Validate() jsonErrors {
+	errors := map[string]map[string]string{}
+
+	if err := c.Bounty.validate(); err != "" {
+		errors["bounty"] = map[string]string{"error": err}
+	}
+
+	if err := c.NoBounty.validate(); err != "" {
+		errors["noBounty"] = map[string]string{"error": err}
+	}
+
+	return errors
+}
+
+// Interval returns how often target is due for kind.
+func (c *ScheduleClasses) Interval(target *Target, kind string) time.Duration {
+	if d := target.Schedule.Interval(kind); d != 0 {
+		return d
+	}
+
+	if c == nil {
+		return 0
+	}
+
+	if target.Bounty != nil && *target.Bounty {
+		return c.Bounty.Interval(kind)
+	}
+	return c.NoBounty.Interval(kind)
+}
+
+// IsDue reports whether target should be handled for kind at now.
+func (c *ScheduleClasses) IsDue(target *Target, kind string, now time.Time) bool {
+	interval := c.Interval(target, kind)
+	if interval == 0 {
+		return true
+	}
+
+	var last *time.Time
+	switch kind {
+	case ScheduleEnumeration:
+		last = target.LastEnumerated
+	case ScheduleResolution:
+		last = target.LastResolved
+	case ScheduleScanning:
+		last = target.LastScanned
+	}
+
+	return last == nil || !now.Before(last.Add(interval))
+}
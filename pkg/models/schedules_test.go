@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleClassesInterval(t *testing.T) {
+	bounty, noBounty := true, false
+	classes := &ScheduleClasses{
+		Bounty:   TargetSchedule{Enumeration: "12h", Scanning: "24h"},
+		NoBounty: TargetSchedule{Enumeration: "72h"},
+	}
+
+	tests := []struct {
+		name    string
+		classes *ScheduleClasses
+		target  Target
+		kind    string
+		want    time.Duration
+	}{
+		{name: "bounty class", classes: classes, target: Target{Bounty: &bounty}, kind: ScheduleEnumeration, want: 12 * time.Hour},
+		{name: "no bounty class", classes: classes, target: Target{Bounty: &noBounty}, kind: ScheduleEnumeration, want: 72 * time.Hour},
+		{name: "unset bounty", classes: classes, target: Target{}, kind: ScheduleEnumeration, want: 72 * time.Hour},
+		{name: "class without kind", classes: classes, target: Target{Bounty: &noBounty}, kind: ScheduleScanning, want: 0},
+		{
+			name:    "own schedule",
+			classes: classes,
+			target:  Target{Bounty: &bounty, Schedule: &TargetSchedule{Enumeration: "1h"}},
+			kind:    ScheduleEnumeration,
+			want:    time.Hour,
+		},
+		{
+			name:    "own schedule without kind",
+			classes: classes,
+			target:  Target{Bounty: &bounty, Schedule: &TargetSchedule{Enumeration: "1h"}},
+			kind:    ScheduleScanning,
+			want:    24 * time.Hour,
+		},
+		{name: "no classes", classes: nil, target: Target{Bounty: &bounty}, kind: ScheduleEnumeration, want: 0},
+		{
+			name:    "no classes own schedule",
+			classes: nil,
+			target:  Target{Schedule: &TargetSchedule{Resolution: "30m"}},
+			kind:    ScheduleResolution,
+			want:    30 * time.Minute,
+		},
+		{name: "unknown kind", classes: classes, target: Target{Bounty: &bounty}, kind: "nope", want: 0},
+	}
+
+	for _, test := range tests {
+		if got := test.classes.Interval(&test.target, test.kind); got != test.want {
+			t.Errorf("%s: Interval = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+
+	classes := &ScheduleClasses{NoBounty: TargetSchedule{Enumeration: "12h", Resolution: "1h", Scanning: "24h"}}
+
+	tests := []struct {
+		name   string
+		target Target
+		kind   string
+		want   bool
+	}{
+		{name: "never ran", target: Target{}, kind: ScheduleEnumeration, want: true},
+		{name: "ran recently", target: Target{LastEnumerated: at(time.Hour)}, kind: ScheduleEnumeration, want: false},
+		{name: "interval went by", target: Target{LastEnumerated: at(12 * time.Hour)}, kind: ScheduleEnumeration, want: true},
+		{name: "long ago", target: Target{LastEnumerated: at(100 * time.Hour)}, kind: ScheduleEnumeration, want: true},
+		{name: "resolution", target: Target{LastResolved: at(30 * time.Minute)}, kind: ScheduleResolution, want: false},
+		{name: "resolution due", target: Target{LastResolved: at(61 * time.Minute)}, kind: ScheduleResolution, want: true},
+		{name: "scanning", target: Target{LastScanned: at(23 * time.Hour)}, kind: ScheduleScanning, want: false},
+		{
+			name:   "other kind ran",
+			target: Target{LastEnumerated: at(time.Minute)},
+			kind:   ScheduleScanning,
+			want:   true,
+		},
+		{
+			name:   "own schedule wins",
+			target: Target{LastEnumerated: at(2 * time.Hour), Schedule: &TargetSchedule{Enumeration: "1h"}},
+			kind:   ScheduleEnumeration,
+			want:   true,
+		},
+	}
+
+	for _, test := range tests {
+		if got := classes.IsDue(&test.target, test.kind, now); got != test.want {
+			t.Errorf("%s: IsDue = %t, want %t", test.name, got, test.want)
+		}
+	}
+
+	// Without any interval a target is handled on every run.
+	var none *ScheduleClasses
+	if !none.IsDue(&Target{LastEnumerated: at(time.Second)}, ScheduleEnumeration, now) {
+		t.Error("IsDue without an interval = false, want true")
+	}
+}
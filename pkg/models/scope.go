@@ -0,0 +1,335 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type ScopeKind string
+
+const (
+	WildcardScope ScopeKind = "wildcard"
+	HostScope     ScopeKind = "host"
+	CidrScope     ScopeKind = "cidr"
+	UrlScope      ScopeKind = "url"
+	RegexScope    ScopeKind = "regex"
+)
+
+// ScopeRule is a single parsed scope entry. Supported forms are:
+//
+//	*.example.com          every subdomain of example.com, not the apex itself
+//	api.example.com        exactly this host (or IP address)
+//	10.0.0.0/24            every IP address in the range
+//	https://example.com/x  every URL on that origin whose path starts with /x
+//	re:^api\d+\.example\.com$  hostnames matching the regex (/regex/ works too)
+//
+// Regexes are matched case-insensitively against the whole hostname, as if
+// they were wrapped in ^(?:...)$, so re:example\.com doesn't cover
+// example.com.attacker.net.
+type ScopeRule struct {
+	Raw  string
+	Kind ScopeKind
+
+	host    string
+	network *net.IPNet
+	url     *url.URL
+	regex   *regexp.Regexp
+}
+
+func ParseScopeRule(entry string) (*ScopeRule, error) {
+	raw := strings.TrimSpace(entry)
+	rule := &ScopeRule{Raw: raw}
+
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("empty entry")
+
+	case strings.HasPrefix(raw, "re:"), len(raw) > 2 && raw[0] == '/' && raw[len(raw)-1] == '/':
+		pattern := strings.TrimPrefix(raw, "re:")
+		if !strings.HasPrefix(raw, "re:") {
+			pattern = raw[1 : len(raw)-1]
+		}
+
+		re, err := regexp.Compile("(?i)^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		rule.Kind, rule.regex = RegexScope, re
+
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(strings.ToLower(raw))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid url")
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+		}
+		rule.Kind, rule.url = UrlScope, u
+
+	case strings.Contains(raw, "/"):
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr: %w", err)
+		}
+		rule.Kind, rule.network = CidrScope, network
+
+	case strings.HasPrefix(raw, "*."):
+		root := strings.ToLower(raw[2:])
+		if !validHost(root) {
+			return nil, fmt.Errorf("invalid wildcard domain")
+		}
+		rule.Kind, rule.host = WildcardScope, root
+
+	default:
+		host := strings.TrimSuffix(strings.ToLower(raw), ".")
+		if net.ParseIP(host) == nil && !validHost(host) {
+			return nil, fmt.Errorf("invalid host")
+		}
+		rule.Kind, rule.host = HostScope, host
+	}
+
+	return rule, nil
+}
+
+var hostPattern = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_\-]*[a-z0-9_])?(\.[a-z0-9_]([a-z0-9_\-]*[a-z0-9_])?)+$`)
+
+func validHost(host string) bool {
+	return hostPattern.MatchString(host)
+}
+
+// Match reports whether the rule covers asset. Assets can be bare hosts,
+// host:port pairs, IP addresses or full URLs.
+//
+// When partial is set, a URL rule also covers the bare host it points at,
+// which is what we want when deciding whether a host may be probed at all.
+// Without it, a host is only covered by a URL rule spanning the whole origin,
+// which is what we want for exclusions.
+func (r *ScopeRule) Match(asset string, partial bool) bool {
+	a := parseAsset(asset)
+
+	switch r.Kind {
+	case WildcardScope:
+		return strings.HasSuffix(a.host, "."+r.host)
+
+	case HostScope:
+		return a.host == r.host
+
+	case CidrScope:
+		ip := net.ParseIP(a.host)
+		return ip != nil && r.network.Contains(ip)
+
+	case RegexScope:
+		return r.regex.MatchString(a.host)
+
+	case UrlScope:
+		if a.host != r.url.Hostname() {
+			return false
+		}
+		if a.scheme != "" && a.scheme != r.url.Scheme {
+			return false
+		}
+		if port := r.url.Port(); port != "" && a.port != "" && a.port != port {
+			return false
+		}
+
+		// An origin without a path, like the hosts of http services, is
+		// treated the same as a bare host.
+		prefix := strings.TrimSuffix(r.url.Path, "/")
+		if a.url == nil || a.path == "" {
+			return partial || prefix == ""
+		}
+		return a.path == prefix || strings.HasPrefix(a.path, prefix+"/")
+	}
+
+	return false
+}
+
+func (r *ScopeRule) String() string {
+	return r.Raw
+}
+
+type asset struct {
+	scheme string
+	host   string
+	port   string
+	path   string
+	url    *url.URL
+}
+
+func parseAsset(raw string) asset {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+
+	if strings.Contains(raw, "://") {
+		if u, err := url.Parse(raw); err == nil {
+			return asset{
+				scheme: u.Scheme,
+				host:   strings.TrimSuffix(u.Hostname(), "."),
+				port:   u.Port(),
+				path:   strings.TrimSuffix(u.Path, "/"),
+				url:    u,
+			}
+		}
+	}
+
+	a := asset{host: raw}
+	if host, port, err := net.SplitHostPort(raw); err == nil {
+		a.host, a.port = host, port
+	}
+	a.host = strings.TrimSuffix(a.host, ".")
+
+	return a
+}
+
+// ScopeMatcher answers whether an asset belongs to a program, given its in
+// scope and out of scope entries. Out of scope entries always win.
+type ScopeMatcher struct {
+	in  []*ScopeRule
+	out []*ScopeRule
+}
+
+func NewScopeMatcher(scope []string, outOfScope []string) (*ScopeMatcher, error) {
+	in, err := parseScopeRules(scope)
+	if err != nil {
+		return nil, fmt.Errorf("scope: %w", err)
+	}
+
+	out, err := parseScopeRules(outOfScope)
+	if err != nil {
+		return nil, fmt.Errorf("outOfScope: %w", err)
+	}
+
+	return &ScopeMatcher{in, out}, nil
+}
+
+func parseScopeRules(entries []string) ([]*ScopeRule, error) {
+	rules := make([]*ScopeRule, 0, len(entries))
+
+	for _, entry := range entries {
+		rule, err := ParseScopeRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %q: %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Excludes returns the out of scope rule covering asset, if any.
+func (s *ScopeMatcher) Excludes(asset string) (*ScopeRule, bool) {
+	for _, rule := range s.out {
+		if rule.Match(asset, false) {
+			return rule, true
+		}
+	}
+
+	return nil, false
+}
+
+// Includes returns the in scope rule covering asset, if any.
+func (s *ScopeMatcher) Includes(asset string) (*ScopeRule, bool) {
+	for _, rule := range s.in {
+		if rule.Match(asset, true) {
+			return rule, true
+		}
+	}
+
+	return nil, false
+}
+
+// Check reports whether asset may be touched, and if not, why.
+func (s *ScopeMatcher) Check(asset string) (bool, string) {
+	if rule, excluded := s.Excludes(asset); excluded {
+		return false, fmt.Sprintf("matches out of scope entry %q", rule.Raw)
+	}
+
+	if _, included := s.Includes(asset); !included {
+		return false, "not covered by any scope entry"
+	}
+
+	return true, ""
+}
+
+// RootDomains returns the domains worth enumerating, one per wildcard entry.
+func (s *ScopeMatcher) RootDomains() []string {
+	return s.hostsOf(WildcardScope)
+}
+
+// Hosts returns the exact hostnames in scope, these don't need enumeration.
+func (s *ScopeMatcher) Hosts() []string {
+	hosts := make([]string, 0)
+	for _, host := range s.hostsOf(HostScope) {
+		if net.ParseIP(host) == nil {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+func (s *ScopeMatcher) hostsOf(kind ScopeKind) []string {
+	seen := map[string]bool{}
+	hosts := make([]string, 0)
+
+	for _, rule := range s.in {
+		if rule.Kind != kind || seen[rule.host] {
+			continue
+		}
+		seen[rule.host] = true
+		hosts = append(hosts, rule.host)
+	}
+
+	return hosts
+}
+
+// LegacyScope rewrites scope entries written before the grammar above, when
+// a bare domain meant the domain along with all of its subdomains, into an
+// apex and a wildcard entry. IP addresses and every other form are kept.
+func LegacyScope(entries []string) []string {
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[strings.ToLower(strings.TrimSpace(entry))] = true
+	}
+
+	scope := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		scope = append(scope, entry)
+
+		rule, err := ParseScopeRule(entry)
+		if err != nil || rule.Kind != HostScope || net.ParseIP(rule.host) != nil {
+			continue
+		}
+
+		if wildcard := "*." + rule.host; !present[wildcard] {
+			present[wildcard] = true
+			scope = append(scope, wildcard)
+		}
+	}
+
+	return scope
+}
+
+// DiffScope returns the entries of after missing from before, and the ones
+// of before missing from after.
+func DiffScope(before []string, after []string) ([]string, []string) {
+	return missingFrom(after, before), missingFrom(before, after)
+}
+
+func missingFrom(entries []string, from []string) []string {
+	present := make(map[string]bool, len(from))
+	for _, entry := range from {
+		present[entry] = true
+	}
+
+	missing := make([]string, 0)
+	for _, entry := range entries {
+		if !present[entry] {
+			missing = append(missing, entry)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseScopeRule(t *testing.T) {
+	tests := []struct {
+		entry string
+		kind  ScopeKind
+		err   bool
+	}{
+		{entry: "*.example.com", kind: WildcardScope},
+		{entry: "api.example.com", kind: HostScope},
+		{entry: "API.Example.com.", kind: HostScope},
+		{entry: "10.0.0.1", kind: HostScope},
+		{entry: "10.0.0.0/24", kind: CidrScope},
+		{entry: "https://example.com/app", kind: UrlScope},
+		{entry: `re:^api\d+\.example\.com$`, kind: RegexScope},
+		{entry: `/^dev\.example\.com$/`, kind: RegexScope},
+		{entry: "", err: true},
+		{entry: "   ", err: true},
+		{entry: "*.", err: true},
+		{entry: "10.0.0.0/33", err: true},
+		{entry: "ftp://example.com", err: true},
+		{entry: "https://", err: true},
+		{entry: "re:(", err: true},
+		{entry: "not a host", err: true},
+	}
+
+	for _, test := range tests {
+		rule, err := ParseScopeRule(test.entry)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseScopeRule(%q) = %v, want an error", test.entry, rule.Kind)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseScopeRule(%q) failed: %v", test.entry, err)
+		} else if rule.Kind != test.kind {
+			t.Errorf("ParseScopeRule(%q).Kind = %s, want %s", test.entry, rule.Kind, test.kind)
+		}
+	}
+}
+
+func TestScopeRuleMatch(t *testing.T) {
+	tests := []struct {
+		rule    string
+		asset   string
+		partial bool
+		want    bool
+	}{
+		{rule: "*.example.com", asset: "api.example.com", want: true},
+		{rule: "*.example.com", asset: "a.b.example.com", want: true},
+		{rule: "*.example.com", asset: "example.com", want: false},
+		{rule: "*.example.com", asset: "badexample.com", want: false},
+		{rule: "*.example.com", asset: "https://api.example.com:8443/x", want: true},
+		{rule: "api.example.com", asset: "api.example.com", want: true},
+		{rule: "api.example.com", asset: "API.example.com.", want: true},
+		{rule: "api.example.com", asset: "api.example.com:443", want: true},
+		{rule: "api.example.com", asset: "dev.api.example.com", want: false},
+		{rule: "10.0.0.0/24", asset: "10.0.0.42", want: true},
+		{rule: "10.0.0.0/24", asset: "10.0.1.1", want: false},
+		{rule: "10.0.0.0/24", asset: "http://10.0.0.7:8080", want: true},
+		{rule: "10.0.0.0/24", asset: "example.com", want: false},
+		{rule: `re:^api\d+\.example\.com$`, asset: "api12.example.com", want: true},
+		{rule: `re:^api\d+\.example\.com$`, asset: "api.example.com", want: false},
+		{rule: `re:example\.com`, asset: "example.com", want: true},
+		{rule: `re:example\.com`, asset: "example.com.attacker.net", want: false},
+		{rule: `re:example\.com`, asset: "dev.example.com", want: false},
+		{rule: `/api|dev\.example\.com/`, asset: "api.example.com", want: false},
+		{rule: `/api|dev\.example\.com/`, asset: "dev.example.com", want: true},
+		{rule: `re:.*\.Example\.COM`, asset: "a.example.com", want: true},
+		{rule: `re:.*\.Example\.COM`, asset: "https://A.EXAMPLE.com/x", want: true},
+		{rule: "https://example.com/app", asset: "https://example.com/app/login", want: true},
+		{rule: "https://example.com/app", asset: "https://example.com/app", want: true},
+		{rule: "https://example.com/app", asset: "https://example.com/application", want: false},
+		{rule: "https://example.com/app", asset: "http://example.com/app", want: false},
+		{rule: "https://example.com/app", asset: "example.com", partial: true, want: true},
+		{rule: "https://example.com/app", asset: "example.com", want: false},
+		{rule: "https://example.com", asset: "example.com", want: true},
+		{rule: "https://example.com:8443/", asset: "https://example.com:9443/", want: false},
+	}
+
+	for _, test := range tests {
+		rule, err := ParseScopeRule(test.rule)
+		if err != nil {
+			t.Fatalf("ParseScopeRule(%q) failed: %v", test.rule, err)
+		}
+
+		if got := rule.Match(test.asset, test.partial); got != test.want {
+			t.Errorf("%q.Match(%q, %v) = %v, want %v", test.rule, test.asset, test.partial, got, test.want)
+		}
+	}
+}
+
+func TestScopeMatcherCheck(t *testing.T) {
+	matcher, err := NewScopeMatcher(
+		[]string{"*.example.com", "example.com", "https://shop.example.org/store"},
+		[]string{"admin.example.com", "re:internal\\..*", "https://shop.example.org/store/admin"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		asset string
+		want  bool
+	}{
+		{asset: "example.com", want: true},
+		{asset: "api.example.com", want: true},
+		{asset: "admin.example.com", want: false},
+		{asset: "internal.example.com", want: false},
+		{asset: "shop.example.org", want: true},
+		{asset: "https://shop.example.org/store/cart", want: true},
+		{asset: "https://shop.example.org/store/admin/users", want: false},
+		{asset: "example.net", want: false},
+	}
+
+	for _, test := range tests {
+		got, reason := matcher.Check(test.asset)
+		if got != test.want {
+			t.Errorf("Check(%q) = %v (%s), want %v", test.asset, got, reason, test.want)
+		}
+		if !got && reason == "" {
+			t.Errorf("Check(%q) gave no reason", test.asset)
+		}
+	}
+
+	if got := matcher.RootDomains(); !slices.Equal(got, []string{"example.com"}) {
+		t.Errorf("RootDomains() = %v", got)
+	}
+	if got := matcher.Hosts(); !slices.Equal(got, []string{"example.com"}) {
+		t.Errorf("Hosts() = %v", got)
+	}
+}
+
+func TestLegacyScope(t *testing.T) {
+	tests := []struct {
+		scope []string
+		want  []string
+	}{
+		{scope: []string{"example.com"}, want: []string{"example.com", "*.example.com"}},
+		{scope: []string{"example.com", "*.example.com"}, want: []string{"example.com", "*.example.com"}},
+		{scope: []string{"*.example.com"}, want: []string{"*.example.com"}},
+		{scope: []string{"10.0.0.1", "10.0.0.0/24"}, want: []string{"10.0.0.1", "10.0.0.0/24"}},
+		{scope: []string{"https://example.com/x", "re:^a$"}, want: []string{"https://example.com/x", "re:^a$"}},
+		{scope: []string{"Example.com"}, want: []string{"Example.com", "*.example.com"}},
+	}
+
+	for _, test := range tests {
+		if got := LegacyScope(test.scope); !slices.Equal(got, test.want) {
+			t.Errorf("LegacyScope(%v) = %v, want %v", test.scope, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskTools lists the tools each task type can run through an adapter
+// instead of a script. Enumeration merges what all of its tools find, the
+// other tasks take a single one.
+var TaskTools = map[string][]string{
+	"subdomain-enumeration": {"subfinder", "amass", "assetfinder"},
+	"dns-resolve":           {"dnsx"},
+	"dns-resolve-all":       {"dnsx"},
+	"http-discovery":        {"httpx"},
+	"http-discovery-all":    {"httpx"},
+	"run-new-templates":     {"nuclei"},
+}
+
+// ToolConfig is how a tool is run, it's stored by tool name under tools in
+// the config collection. Path defaults to the tool's name looked up in PATH,
+// Flags come after the ones its adapter passes and RateLimit caps its
+// requests per second, no cap if it's zero.
+type ToolConfig struct {
+	Path      string   `json:"path,omitempty" bson:"path,omitempty"`
+	Flags     []string `json:"flags,omitempty" bson:"flags,omitempty"`
+	RateLimit int      `json:"rateLimit,omitempty" bson:"rateLimit,omitempty"`
+}
+
+func (c *ToolConfig) Validate() string {
+	if c.RateLimit < 0 {
+		return "rateLimit can't be negative."
+	}
+
+	return ""
+}
+
+func (t *TaskDefinition) validateTools() string {
+	if len(t.Tools) == 0 {
+		return ""
+	}
+
+	allowed := TaskTools[t.Type]
+	if len(allowed) == 0 {
+		return fmt.Sprintf("%s doesn't run through tools.", t.Type)
+	}
+
+	if t.Type != "subdomain-enumeration" && len(t.Tools) > 1 {
+		return fmt.Sprintf("%s takes a single tool.", t.Type)
+	}
+
+	for _, tool := range t.Tools {
+		known := false
+		for _, name := range allowed {
+			known = known || tool == name
+		}
+
+		if !known {
+			return fmt.Sprintf("%s can only use %s.", t.Type, strings.Join(allowed, ", "))
+		}
+	}
+
+	return ""
+}
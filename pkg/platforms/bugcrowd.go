@@ -0,0 +1,66 @@
+package platforms
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type bugcrowdTarget struct {
+	Name     string `json:"name"`
+	Uri      string `json:"uri"`
+	Category string `json:"category"`
+}
+
+type bugcrowdProgram struct {
+	Name         string `json:"name"`
+	Code         string `json:"code"`
+	MaxPayout    int    `json:"max_payout"`
+	TargetGroups []struct {
+		InScope bool             `json:"in_scope"`
+		Targets []bugcrowdTarget `json:"targets"`
+	} `json:"target_groups"`
+}
+
+// parseBugcrowd reads the target groups JSON of one or more Bugcrowd programs.
+func parseBugcrowd(r io.Reader) ([]*Program, error) {
+	exports, err := decodeOneOrMany[bugcrowdProgram](r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bugcrowd json: %w", err)
+	}
+
+	programs := make([]*Program, 0, len(exports))
+	for _, export := range exports {
+		program := &Program{Name: export.Name, Handle: export.Code, Bounty: export.MaxPayout > 0}
+
+		for _, group := range export.TargetGroups {
+			for _, target := range group.Targets {
+				value := target.Uri
+				if value == "" {
+					value = target.Name
+				}
+				program.add(bugcrowdKind(target.Category, value), value, group.InScope)
+			}
+		}
+
+		programs = append(programs, program)
+	}
+
+	return programs, nil
+}
+
+func bugcrowdKind(category string, value string) scopeKind {
+	switch strings.ToLower(category) {
+	case "website", "api":
+		if strings.Contains(value, "*") {
+			return kindWildcard
+		}
+		return kindUrl
+	case "ip_address", "network":
+		return kindCidr
+	case "":
+		return kindUnknown
+	}
+
+	return kindOther
+}
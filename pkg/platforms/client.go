@@ -0,0 +1,177 @@
+package platforms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Endpoint is where and how to reach a platform's API.
+type Endpoint struct {
+	BaseUrl string
+	User    string
+	Token   string
+}
+
+var defaultBaseUrls = map[string]string{
+	"hackerone": "https://api.hackerone.com",
+	"bugcrowd":  "https://api.bugcrowd.com",
+	"integrity": "https://api.intigriti.com/external/researcher",
+	"yeswehack": "https://api.yeswehack.com",
+}
+
+var envPrefixes = map[string]string{
+	"hackerone": "HACKERONE",
+	"bugcrowd":  "BUGCROWD",
+	"integrity": "INTIGRITI",
+	"yeswehack": "YESWEHACK",
+}
+
+// EndpointsFromEnv reads <PLATFORM>_API_URL, <PLATFORM>_API_USER and
+// <PLATFORM>_API_TOKEN for every supported platform, so the sync can be
+// pointed at a local stub instead of the real APIs.
+func EndpointsFromEnv() map[string]Endpoint {
+	endpoints := make(map[string]Endpoint, len(envPrefixes))
+
+	for source, prefix := range envPrefixes {
+		base := os.Getenv(prefix + "_API_URL")
+		if base == "" {
+			base = defaultBaseUrls[source]
+		}
+
+		endpoints[source] = Endpoint{
+			BaseUrl: strings.TrimSuffix(base, "/"),
+			User:    os.Getenv(prefix + "_API_USER"),
+			Token:   os.Getenv(prefix + "_API_TOKEN"),
+		}
+	}
+
+	return endpoints
+}
+
+// Client fetches program scopes from the platforms' APIs.
+type Client struct {
+	endpoints map[string]Endpoint
+	http      *http.Client
+}
+
+func NewClient(endpoints map[string]Endpoint) *Client {
+	return &Client{endpoints, &http.Client{Timeout: 30 * time.Second}}
+}
+
+// FetchProgram returns the current scope of the program with the given
+// handle on source.
+func (c *Client) FetchProgram(ctx context.Context, source string, handle string) (*Program, error) {
+	endpoint, ok := c.endpoints[source]
+	if !ok {
+		return nil, fmt.Errorf("unsupported source %q", source)
+	}
+
+	switch source {
+	case "hackerone":
+		return c.fetchHackerOne(ctx, endpoint, handle)
+	case "bugcrowd":
+		return c.fetchOne(ctx, endpoint, fmt.Sprintf("/programs/%s/target_groups", handle), parseBugcrowd)
+	case "integrity":
+		return c.fetchOne(ctx, endpoint, fmt.Sprintf("/v1/programs/%s", handle), parseIntigriti)
+	case "yeswehack":
+		return c.fetchOne(ctx, endpoint, fmt.Sprintf("/programs/%s", handle), parseYesWeHack)
+	}
+
+	return nil, fmt.Errorf("unsupported source %q", source)
+}
+
+func (c *Client) fetchOne(ctx context.Context, endpoint Endpoint, path string, parse Parser) (*Program, error) {
+	body, err := c.get(ctx, endpoint, endpoint.BaseUrl+path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	programs, err := parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(programs) != 1 {
+		return nil, fmt.Errorf("expected one program from %s, got %d", path, len(programs))
+	}
+
+	return programs[0], nil
+}
+
+type hackerOneScopes struct {
+	Data []struct {
+		Attributes struct {
+			AssetIdentifier       string `json:"asset_identifier"`
+			AssetType             string `json:"asset_type"`
+			EligibleForBounty     bool   `json:"eligible_for_bounty"`
+			EligibleForSubmission bool   `json:"eligible_for_submission"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+func (c *Client) fetchHackerOne(ctx context.Context, endpoint Endpoint, handle string) (*Program, error) {
+	program := &Program{Handle: handle}
+	next := fmt.Sprintf("%s/v1/hackers/programs/%s/structured_scopes?page[size]=100", endpoint.BaseUrl, handle)
+
+	for next != "" {
+		body, err := c.get(ctx, endpoint, next)
+		if err != nil {
+			return nil, err
+		}
+
+		var page hackerOneScopes
+		err = json.NewDecoder(body).Decode(&page)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hackerone response: %w", err)
+		}
+
+		for _, scope := range page.Data {
+			attrs := scope.Attributes
+			if attrs.EligibleForSubmission && attrs.EligibleForBounty {
+				program.Bounty = true
+			}
+			program.add(hackerOneKind(attrs.AssetType), attrs.AssetIdentifier, attrs.EligibleForSubmission)
+		}
+
+		next = page.Links.Next
+	}
+
+	return program, nil
+}
+
+func (c *Client) get(ctx context.Context, endpoint Endpoint, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case endpoint.User != "":
+		req.SetBasicAuth(endpoint.User, endpoint.Token)
+	case endpoint.Token != "":
+		req.Header.Set("Authorization", "Bearer "+endpoint.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %s: %s", url, resp.Status)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,96 @@
+package platforms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetchProgram(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/hackers/programs/example/structured_scopes":
+			if user, token, ok := r.BasicAuth(); !ok || user != "hacker" || token != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if r.URL.Query().Get("page[number]") == "" {
+				fmt.Fprintf(w, `{"data": [
+					{"attributes": {"asset_identifier": "*.example.com", "asset_type": "WILDCARD", "eligible_for_bounty": true, "eligible_for_submission": true}}
+				], "links": {"next": "%s/v1/hackers/programs/example/structured_scopes?page[size]=100&page[number]=2"}}`, srv.URL)
+				return
+			}
+			fmt.Fprint(w, `{"data": [
+				{"attributes": {"asset_identifier": "blog.example.com", "asset_type": "URL", "eligible_for_submission": false}},
+				{"attributes": {"asset_identifier": "com.example.app", "asset_type": "GOOGLE_PLAY_APP_ID", "eligible_for_submission": true}}
+			], "links": {}}`)
+
+		case "/programs/example":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"title": "Example", "slug": "example", "scopes": [{"scope": "api.example.com", "scope_type": "api"}]}`)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(map[string]Endpoint{
+		"hackerone": {BaseUrl: srv.URL, User: "hacker", Token: "secret"},
+		"yeswehack": {BaseUrl: srv.URL, Token: "token"},
+		"bugcrowd":  {BaseUrl: srv.URL},
+	})
+
+	tests := []struct {
+		source string
+		handle string
+		want   *Program
+	}{
+		{
+			source: "hackerone",
+			handle: "example",
+			want: &Program{
+				Handle:     "example",
+				Bounty:     true,
+				Scope:      []string{"*.example.com"},
+				OutOfScope: []string{"blog.example.com"},
+				Skipped:    map[string]string{"com.example.app": "unsupported asset type"},
+			},
+		},
+		{
+			source: "yeswehack",
+			handle: "example",
+			want:   &Program{Name: "Example", Handle: "example", Scope: []string{"api.example.com"}},
+		},
+		// Not found on the server.
+		{source: "bugcrowd", handle: "example"},
+		// No endpoint configured.
+		{source: "integrity", handle: "example"},
+	}
+
+	for _, test := range tests {
+		got, err := client.FetchProgram(context.Background(), test.source, test.handle)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: FetchProgram succeeded, want an error", test.source)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: FetchProgram failed: %v", test.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: FetchProgram = %+v, want %+v", test.source, *got, *test.want)
+		}
+	}
+}
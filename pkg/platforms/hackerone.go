@@ -0,0 +1,72 @@
+package platforms
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseHackerOne reads the scope CSV HackerOne offers on a program's policy
+// page. The export doesn't carry the program's name, the caller sets it.
+func parseHackerOne(r io.Reader) ([]*Program, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid hackerone csv: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+
+	for _, required := range []string{"identifier", "asset_type", "eligible_for_submission"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid hackerone csv: missing %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	program := &Program{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid hackerone csv: %w", err)
+		}
+
+		inScope := strings.EqualFold(field(record, "eligible_for_submission"), "true")
+		if inScope && strings.EqualFold(field(record, "eligible_for_bounty"), "true") {
+			program.Bounty = true
+		}
+
+		program.add(hackerOneKind(field(record, "asset_type")), field(record, "identifier"), inScope)
+	}
+
+	return []*Program{program}, nil
+}
+
+func hackerOneKind(assetType string) scopeKind {
+	switch strings.ToUpper(assetType) {
+	case "WILDCARD":
+		return kindWildcard
+	case "URL":
+		return kindUrl
+	case "CIDR", "IP_ADDRESS":
+		return kindCidr
+	}
+
+	return kindOther
+}
@@ -0,0 +1,60 @@
+package platforms
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type intigritiValue struct {
+	Value string `json:"value"`
+}
+
+type intigritiProgram struct {
+	Name      string `json:"name"`
+	Handle    string `json:"handle"`
+	MaxBounty struct {
+		Value float64 `json:"value"`
+	} `json:"maxBounty"`
+	Domains []struct {
+		Type     intigritiValue `json:"type"`
+		Endpoint string         `json:"endpoint"`
+		Tier     intigritiValue `json:"tier"`
+	} `json:"domains"`
+}
+
+// parseIntigriti reads the program details JSON of one or more Intigriti
+// programs, entries in the "Out Of Scope" tier end up in OutOfScope.
+func parseIntigriti(r io.Reader) ([]*Program, error) {
+	exports, err := decodeOneOrMany[intigritiProgram](r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid intigriti json: %w", err)
+	}
+
+	programs := make([]*Program, 0, len(exports))
+	for _, export := range exports {
+		program := &Program{Name: export.Name, Handle: export.Handle, Bounty: export.MaxBounty.Value > 0}
+
+		for _, domain := range export.Domains {
+			inScope := !strings.EqualFold(domain.Tier.Value, "Out Of Scope")
+			program.add(intigritiKind(domain.Type.Value), domain.Endpoint, inScope)
+		}
+
+		programs = append(programs, program)
+	}
+
+	return programs, nil
+}
+
+func intigritiKind(domainType string) scopeKind {
+	switch strings.ToLower(domainType) {
+	case "wildcard":
+		return kindWildcard
+	case "url":
+		return kindUrl
+	case "iprange", "ip range", "ip":
+		return kindCidr
+	}
+
+	return kindOther
+}
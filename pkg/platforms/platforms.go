@@ -0,0 +1,190 @@
+package platforms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	m "github.com/ArCaneSec/eagleeye/pkg/models"
+)
+
+// Program is a program's scope as a platform describes it, already mapped to
+// the scope grammar understood by models.ScopeMatcher.
+type Program struct {
+	Name       string   `json:"name"`
+	Handle     string   `json:"handle"`
+	Bounty     bool     `json:"bounty"`
+	Scope      []string `json:"scope"`
+	OutOfScope []string `json:"outOfScope"`
+
+	// Skipped holds entries we can't express as scope rules, like mobile
+	// apps or source code repositories, keyed by their original value.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// Target builds the target document for a program imported from source.
+func (p *Program) Target(source string) m.Target {
+	bounty := p.Bounty
+
+	return m.Target{
+		Name:       p.Name,
+		Handle:     p.Handle,
+		Bounty:     &bounty,
+		Scope:      p.Scope,
+		OutOfScope: p.OutOfScope,
+		Source:     source,
+	}
+}
+
+// Parser reads a platform's scope export and returns the programs in it.
+type Parser func(io.Reader) ([]*Program, error)
+
+var parsers = map[string]Parser{
+	"hackerone": parseHackerOne,
+	"bugcrowd":  parseBugcrowd,
+	"integrity": parseIntigriti,
+	"yeswehack": parseYesWeHack,
+}
+
+func Parse(source string, r io.Reader) ([]*Program, error) {
+	parser, ok := parsers[source]
+	if !ok {
+		return nil, fmt.Errorf("unsupported source %q", source)
+	}
+
+	return parser(r)
+}
+
+// scopeKind is the broad kind of asset a platform entry describes, platform
+// specific types are mapped to one of these before normalizing the value.
+type scopeKind int
+
+const (
+	kindUnknown scopeKind = iota
+	kindWildcard
+	kindUrl
+	kindCidr
+	kindOther
+)
+
+func (p *Program) add(kind scopeKind, value string, inScope bool) {
+	entry, reason := normalize(kind, value)
+	if entry == "" {
+		if p.Skipped == nil {
+			p.Skipped = map[string]string{}
+		}
+		p.Skipped[value] = reason
+		return
+	}
+
+	if inScope {
+		p.Scope = appendUnique(p.Scope, entry)
+	} else {
+		p.OutOfScope = appendUnique(p.OutOfScope, entry)
+	}
+}
+
+// normalize turns a platform entry into a scope rule, or returns the reason
+// it can't be used.
+func normalize(kind scopeKind, value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", "empty value"
+	}
+
+	var entry string
+
+	switch kind {
+	case kindOther:
+		return "", "unsupported asset type"
+
+	case kindCidr:
+		entry = value
+		if ip := net.ParseIP(value); ip != nil {
+			entry = ip.String()
+		}
+
+	default:
+		entry = normalizeHost(value)
+		if kind == kindWildcard && !strings.HasPrefix(entry, "*.") && !strings.Contains(entry, "/") {
+			entry = "*." + entry
+		}
+	}
+
+	if _, err := m.ParseScopeRule(entry); err != nil {
+		return "", err.Error()
+	}
+
+	return entry, ""
+}
+
+// normalizeHost maps the usual ways programs write web assets to either a
+// wildcard, a bare host or a URL prefix.
+func normalizeHost(value string) string {
+	value = strings.ToLower(value)
+
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return value
+		}
+		if strings.HasPrefix(u.Host, "*") {
+			return normalizeHost(u.Host)
+		}
+		if strings.Trim(u.Path, "/*") == "" {
+			return u.Host
+		}
+		return fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, strings.TrimSuffix(u.Path, "*"))
+	}
+
+	if i := strings.Index(value, "/"); i != -1 {
+		if strings.Trim(value[i:], "/*") == "" {
+			return normalizeHost(value[:i])
+		}
+		return normalizeHost("https://" + value)
+	}
+
+	if strings.HasPrefix(value, "*") {
+		return "*." + strings.TrimLeft(value, "*.")
+	}
+
+	return value
+}
+
+func appendUnique(entries []string, entry string) []string {
+	for _, e := range entries {
+		if e == entry {
+			return entries
+		}
+	}
+
+	return append(entries, entry)
+}
+
+// decodeOneOrMany decodes either a single JSON object or an array of them.
+func decodeOneOrMany[T any](r io.Reader) ([]T, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) != 0 && data[0] == '[' {
+		var many []T
+		if err := json.Unmarshal(data, &many); err != nil {
+			return nil, err
+		}
+		return many, nil
+	}
+
+	var one T
+	if err := json.Unmarshal(data, &one); err != nil {
+		return nil, err
+	}
+
+	return []T{one}, nil
+}
@@ -0,0 +1,189 @@
+package platforms
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "example.com", want: "example.com"},
+		{value: "App.Example.com", want: "app.example.com"},
+		{value: "*.example.com", want: "*.example.com"},
+		{value: "**.example.com", want: "*.example.com"},
+		{value: "*example.com", want: "*.example.com"},
+		{value: "https://app.example.com", want: "app.example.com"},
+		{value: "https://app.example.com/", want: "app.example.com"},
+		{value: "https://app.example.com/*", want: "app.example.com"},
+		{value: "https://*.example.com", want: "*.example.com"},
+		{value: "https://app.example.com/api/*", want: "https://app.example.com/api/"},
+		{value: "http://app.example.com:8080/admin", want: "http://app.example.com:8080/admin"},
+		{value: "app.example.com/", want: "app.example.com"},
+		{value: "app.example.com/admin", want: "https://app.example.com/admin"},
+	}
+
+	for _, test := range tests {
+		if got := normalizeHost(test.value); got != test.want {
+			t.Errorf("normalizeHost(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		kind   scopeKind
+		value  string
+		want   string
+		reason string
+	}{
+		{kind: kindWildcard, value: "example.com", want: "*.example.com"},
+		{kind: kindWildcard, value: " *.Example.com ", want: "*.example.com"},
+		{kind: kindWildcard, value: "https://*.example.com", want: "*.example.com"},
+		{kind: kindUrl, value: "https://app.example.com/", want: "app.example.com"},
+		{kind: kindUrl, value: "https://app.example.com/api", want: "https://app.example.com/api"},
+		{kind: kindUnknown, value: "*.example.com", want: "*.example.com"},
+		{kind: kindUnknown, value: "api.example.com", want: "api.example.com"},
+		{kind: kindCidr, value: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{kind: kindCidr, value: "192.168.1.1", want: "192.168.1.1"},
+		{kind: kindCidr, value: "10.0.0.0/33", reason: "invalid cidr"},
+		{kind: kindOther, value: "com.example.app", reason: "unsupported asset type"},
+		{kind: kindUrl, value: "  ", reason: "empty value"},
+		{kind: kindUnknown, value: "Anything hosted by a third party", reason: "invalid host"},
+	}
+
+	for _, test := range tests {
+		got, reason := normalize(test.kind, test.value)
+		if got != test.want {
+			t.Errorf("normalize(%d, %q) = %q, want %q", test.kind, test.value, got, test.want)
+		}
+		if !strings.Contains(reason, test.reason) || test.want != "" && reason != "" {
+			t.Errorf("normalize(%d, %q) reason = %q, want %q", test.kind, test.value, reason, test.reason)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source string
+		input  string
+		want   []*Program
+	}{
+		{
+			source: "hackerone",
+			input: "identifier,asset_type,instruction,eligible_for_bounty,eligible_for_submission,max_severity\n" +
+				"*.example.com,WILDCARD,,true,true,critical\n" +
+				"https://app.example.com/,URL,,true,true,critical\n" +
+				"10.0.0.0/24,CIDR,,false,true,critical\n" +
+				"com.example.app,GOOGLE_PLAY_APP_ID,,true,true,critical\n" +
+				"blog.example.com,URL,,false,false,none\n",
+			want: []*Program{{
+				Bounty:     true,
+				Scope:      []string{"*.example.com", "app.example.com", "10.0.0.0/24"},
+				OutOfScope: []string{"blog.example.com"},
+				Skipped:    map[string]string{"com.example.app": "unsupported asset type"},
+			}},
+		},
+		{
+			source: "bugcrowd",
+			input: `{"name": "Example", "code": "example", "max_payout": 5000, "target_groups": [
+				{"in_scope": true, "targets": [
+					{"name": "*.example.com", "category": "website"},
+					{"name": "API", "uri": "https://api.example.com/v2", "category": "api"},
+					{"name": "10.1.0.0/16", "category": "network"},
+					{"name": "Example iOS", "category": "ios"}
+				]},
+				{"in_scope": false, "targets": [{"name": "status.example.com", "category": "website"}]}
+			]}`,
+			want: []*Program{{
+				Name:       "Example",
+				Handle:     "example",
+				Bounty:     true,
+				Scope:      []string{"*.example.com", "https://api.example.com/v2", "10.1.0.0/16"},
+				OutOfScope: []string{"status.example.com"},
+				Skipped:    map[string]string{"Example iOS": "unsupported asset type"},
+			}},
+		},
+		{
+			source: "integrity",
+			input: `[
+				{"name": "Example", "handle": "example", "maxBounty": {"value": 0}, "domains": [
+					{"type": {"value": "Wildcard"}, "endpoint": "example.com", "tier": {"value": "Tier 1"}},
+					{"type": {"value": "Url"}, "endpoint": "shop.example.com/*", "tier": {"value": "Tier 2"}},
+					{"type": {"value": "IpRange"}, "endpoint": "172.16.0.0/12", "tier": {"value": "Tier 3"}},
+					{"type": {"value": "Url"}, "endpoint": "legacy.example.com", "tier": {"value": "Out Of Scope"}}
+				]},
+				{"name": "Other", "handle": "other", "maxBounty": {"value": 1500}, "domains": []}
+			]`,
+			want: []*Program{
+				{
+					Name:       "Example",
+					Handle:     "example",
+					Scope:      []string{"*.example.com", "shop.example.com", "172.16.0.0/12"},
+					OutOfScope: []string{"legacy.example.com"},
+				},
+				{Name: "Other", Handle: "other", Bounty: true},
+			},
+		},
+		{
+			source: "yeswehack",
+			input: `{"title": "Example", "slug": "example", "bounty": true,
+				"scopes": [
+					{"scope": "*.example.com", "scope_type": "web-application"},
+					{"scope": "https://api.example.com", "scope_type": "api"},
+					{"scope": "192.0.2.10", "scope_type": "ip-address"},
+					{"scope": "https://github.com/example/app", "scope_type": "source-code"}
+				],
+				"out_of_scope": ["dev.example.com", "Any social engineering"]}`,
+			want: []*Program{{
+				Name:       "Example",
+				Handle:     "example",
+				Bounty:     true,
+				Scope:      []string{"*.example.com", "api.example.com", "192.0.2.10"},
+				OutOfScope: []string{"dev.example.com"},
+				Skipped: map[string]string{
+					"https://github.com/example/app": "unsupported asset type",
+					"Any social engineering":         "invalid host",
+				},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.source, strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: Parse failed: %v", test.source, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Parse =", test.source)
+			for _, program := range got {
+				t.Errorf("\t%+v", *program)
+			}
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		source string
+		input  string
+	}{
+		{source: "hackerone", input: ""},
+		{source: "hackerone", input: "identifier,asset_type\nexample.com,URL\n"},
+		{source: "bugcrowd", input: "not json"},
+		{source: "integrity", input: `[{"name": 1}]`},
+		{source: "yeswehack", input: `{"scopes": "*.example.com"}`},
+		{source: "somewhere", input: "{}"},
+	}
+
+	for _, test := range tests {
+		if _, err := Parse(test.source, strings.NewReader(test.input)); err == nil {
+			t.Errorf("%s: Parse(%q) succeeded, want an error", test.source, test.input)
+		}
+	}
+}
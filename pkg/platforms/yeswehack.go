@@ -0,0 +1,60 @@
+package platforms
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type yesWeHackProgram struct {
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+	Bounty bool   `json:"bounty"`
+	Scopes []struct {
+		Scope     string `json:"scope"`
+		ScopeType string `json:"scope_type"`
+	} `json:"scopes"`
+	OutOfScope []string `json:"out_of_scope"`
+}
+
+// parseYesWeHack reads the program JSON of one or more YesWeHack programs.
+// Their out of scope list is free text, lines that aren't assets are skipped.
+func parseYesWeHack(r io.Reader) ([]*Program, error) {
+	exports, err := decodeOneOrMany[yesWeHackProgram](r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid yeswehack json: %w", err)
+	}
+
+	programs := make([]*Program, 0, len(exports))
+	for _, export := range exports {
+		program := &Program{Name: export.Title, Handle: export.Slug, Bounty: export.Bounty}
+
+		for _, scope := range export.Scopes {
+			program.add(yesWeHackKind(scope.ScopeType, scope.Scope), scope.Scope, true)
+		}
+
+		for _, entry := range export.OutOfScope {
+			program.add(kindUnknown, entry, false)
+		}
+
+		programs = append(programs, program)
+	}
+
+	return programs, nil
+}
+
+func yesWeHackKind(scopeType string, value string) scopeKind {
+	switch strings.ToLower(scopeType) {
+	case "web-application", "api":
+		if strings.Contains(value, "*") {
+			return kindWildcard
+		}
+		return kindUrl
+	case "ip-address":
+		return kindCidr
+	case "":
+		return kindUnknown
+	}
+
+	return kindOther
+}